@@ -242,15 +242,14 @@ func TestBasic_Passwd(t *testing.T) {
 	defer purge(t, ctx, "passwd")()
 
 	_ = run(t, ctx, "nomad", "job", "run", "./jobs/passwd.hcl")
+	statusOutput := run(t, ctx, "nomad", "job", "status", "passwd")
+	alloc := allocFromJobStatus(t, statusOutput)
 
-	// make sure job is failing (cannot read /etc/passwd)
-	time.Sleep(20 * time.Second)
-	jobStatus := run(t, ctx, "nomad", "job", "status", "passwd")
-	must.RegexMatch(t, deadRe, jobStatus)
-
-	// stop the job and check complete
-	stopOutput := run(t, ctx, "nomad", "job", "stop", "-purge", "passwd")
-	must.StrContains(t, stopOutput, `finished with status "complete"`)
+	// the dynamic user reads a synthesized /etc/passwd with its own entry,
+	// and none of the users of the host
+	output := logs(t, ctx, alloc)
+	must.RegexMatch(t, regexp.MustCompile(`nomad-\d+:x:\d+:\d+:nomad workload user:\S+/home:/bin/sh`), output)
+	must.StrNotContains(t, output, "/bin/bash")
 }
 
 func TestBasic_Cgroup(t *testing.T) {
@@ -261,8 +260,9 @@ func TestBasic_Cgroup(t *testing.T) {
 
 	statusOutput := run(t, ctx, "nomad", "job", "status", "cgroup")
 
+	// inside the cgroup namespace the task cgroup is the root cgroup
 	alloc := allocFromJobStatus(t, statusOutput)
-	cgroupRe := regexp.MustCompile(`0::/nomad\.slice/share.slice/` + alloc + `.+\.cat\.scope`)
+	cgroupRe := regexp.MustCompile(`^0::/$`)
 
 	output := logs(t, ctx, alloc)
 	must.RegexMatch(t, cgroupRe, output)
@@ -25,8 +25,7 @@ job "resources" {
 
       config {
         command = "cat"
-        args    = ["/sys/fs/cgroup/nomad.slice/share.slice/${NOMAD_ALLOC_ID}.${NOMAD_TASK_NAME}.scope/memory.max"]
-        unveil  = ["r:/sys/fs/cgroup/nomad.slice"]
+        args    = ["/sys/fs/cgroup/memory.max"]
       }
       resources {
         cpu    = 100
@@ -38,8 +37,7 @@ job "resources" {
       driver = "exec2"
       config {
         command = "cat"
-        args    = ["/sys/fs/cgroup/nomad.slice/share.slice/${NOMAD_ALLOC_ID}.${NOMAD_TASK_NAME}.scope/memory.max"]
-        unveil  = ["r:/sys/fs/cgroup/nomad.slice"]
+        args    = ["/sys/fs/cgroup/memory.max"]
       }
       resources {
         cpu        = 100
@@ -52,8 +50,7 @@ job "resources" {
       driver = "exec2"
       config {
         command = "cat"
-        args    = ["/sys/fs/cgroup/nomad.slice/share.slice/${NOMAD_ALLOC_ID}.${NOMAD_TASK_NAME}.scope/memory.low"]
-        unveil  = ["r:/sys/fs/cgroup/nomad.slice"]
+        args    = ["/sys/fs/cgroup/memory.low"]
       }
       resources {
         cpu        = 100
@@ -66,8 +63,7 @@ job "resources" {
       driver = "exec2"
       config {
         command = "cat"
-        args    = ["/sys/fs/cgroup/nomad.slice/share.slice/${NOMAD_ALLOC_ID}.${NOMAD_TASK_NAME}.scope/cpu.max"]
-        unveil  = ["r:/sys/fs/cgroup/nomad.slice"]
+        args    = ["/sys/fs/cgroup/cpu.max"]
       }
       resources {
         cpu = 1000
@@ -78,8 +74,7 @@ job "resources" {
       driver = "exec2"
       config {
         command = "cat"
-        args    = ["/sys/fs/cgroup/nomad.slice/reserve.slice/${NOMAD_ALLOC_ID}.${NOMAD_TASK_NAME}.scope/cpu.max"]
-        unveil  = ["r:/sys/fs/cgroup/nomad.slice"]
+        args    = ["/sys/fs/cgroup/cpu.max"]
       }
       resources {
         cores = 1
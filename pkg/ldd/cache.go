@@ -0,0 +1,95 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+// Package ldd finds the files needed to execute a program, i.e. its ELF
+// interpreter and shared libraries, the way the dynamic linker finds them.
+package ldd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// CacheFile is the cache of shared libraries maintained by ldconfig.
+const CacheFile = "/etc/ld.so.cache"
+
+const (
+	cacheMagicOld = "ld.so-1.7.0"
+	cacheMagicNew = "glibc-ld.so.cache1.1"
+
+	cacheHeaderOld = 16 // magic, nlibs
+	cacheEntryOld  = 12 // flags, key, value
+	cacheHeaderNew = 48 // magic, version, nlibs, len_strings, flags, extension, unused
+	cacheEntryNew  = 24 // flags, key, value, osversion, hwcap
+)
+
+// Cache maps the names of shared libraries to their paths, in the order of
+// preference of the dynamic linker. A name may have paths for several
+// architectures.
+type Cache map[string][]string
+
+// ReadCache reads the given ld.so.cache file. A missing cache file results in
+// an empty cache, as on systems without ldconfig.
+func ReadCache(path string) (Cache, error) {
+	b, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return Cache{}, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to read ld.so cache: %w", err)
+	}
+	return parseCache(b)
+}
+
+// parseCache parses the content of an ld.so.cache file, in the format of
+// glibc 2.32 and later, optionally preceded by the old format.
+func parseCache(b []byte) (Cache, error) {
+	if bytes.HasPrefix(b, []byte(cacheMagicOld)) {
+		if len(b) < cacheHeaderOld {
+			return nil, fmt.Errorf("ld.so cache is truncated")
+		}
+		nlibs := int(binary.LittleEndian.Uint32(b[12:]))
+		offset := cacheHeaderOld + nlibs*cacheEntryOld
+		offset = (offset + 7) &^ 7 // aligned for the new format
+		if offset > len(b) {
+			return nil, fmt.Errorf("ld.so cache is truncated")
+		}
+		b = b[offset:]
+	}
+
+	if !bytes.HasPrefix(b, []byte(cacheMagicNew)) || len(b) < cacheHeaderNew {
+		return nil, fmt.Errorf("ld.so cache has unknown format")
+	}
+
+	nlibs := int(binary.LittleEndian.Uint32(b[20:]))
+	if cacheHeaderNew+nlibs*cacheEntryNew > len(b) {
+		return nil, fmt.Errorf("ld.so cache is truncated")
+	}
+
+	// the strings of the entries are offsets from the start of the header
+	str := func(offset uint32) string {
+		if int(offset) >= len(b) {
+			return ""
+		}
+		s := b[offset:]
+		if end := bytes.IndexByte(s, 0); end >= 0 {
+			s = s[:end]
+		}
+		return string(s)
+	}
+
+	cache := make(Cache, nlibs)
+	for i := range nlibs {
+		entry := b[cacheHeaderNew+i*cacheEntryNew:]
+		key := str(binary.LittleEndian.Uint32(entry[4:]))
+		value := str(binary.LittleEndian.Uint32(entry[8:]))
+		if key == "" || value == "" {
+			continue
+		}
+		cache[key] = append(cache[key], value)
+	}
+	return cache, nil
+}
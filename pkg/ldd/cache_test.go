@@ -0,0 +1,74 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package ldd
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/shoenig/test/must"
+)
+
+// newCache encodes a cache in the new format, with entries of name and path.
+func newCache(entries ...[2]string) []byte {
+	strings := cacheHeaderNew + len(entries)*cacheEntryNew
+	b := make([]byte, strings)
+	copy(b, cacheMagicNew)
+	binary.LittleEndian.PutUint32(b[20:], uint32(len(entries)))
+
+	for i, entry := range entries {
+		key := len(b)
+		b = append(b, entry[0]+"\x00"...)
+		value := len(b)
+		b = append(b, entry[1]+"\x00"...)
+
+		e := b[cacheHeaderNew+i*cacheEntryNew:]
+		binary.LittleEndian.PutUint32(e[0:], 0x0303)
+		binary.LittleEndian.PutUint32(e[4:], uint32(key))
+		binary.LittleEndian.PutUint32(e[8:], uint32(value))
+	}
+	return b
+}
+
+func Test_parseCache(t *testing.T) {
+	b := newCache(
+		[2]string{"libc.so.6", "/lib/x86_64-linux-gnu/libc.so.6"},
+		[2]string{"libc.so.6", "/lib/i386-linux-gnu/libc.so.6"},
+		[2]string{"libz.so.1", "/lib/x86_64-linux-gnu/libz.so.1"},
+	)
+
+	cache, err := parseCache(b)
+	must.NoError(t, err)
+	must.Eq(t, Cache{
+		"libc.so.6": {"/lib/x86_64-linux-gnu/libc.so.6", "/lib/i386-linux-gnu/libc.so.6"},
+		"libz.so.1": {"/lib/x86_64-linux-gnu/libz.so.1"},
+	}, cache)
+}
+
+func Test_parseCache_old(t *testing.T) {
+	// an old format header with one (ignored) entry, padded to 8 bytes
+	old := make([]byte, 32)
+	copy(old, cacheMagicOld)
+	binary.LittleEndian.PutUint32(old[12:], 1)
+
+	b := append(old, newCache([2]string{"libz.so.1", "/usr/lib/libz.so.1"})...)
+	cache, err := parseCache(b)
+	must.NoError(t, err)
+	must.Eq(t, Cache{"libz.so.1": {"/usr/lib/libz.so.1"}}, cache)
+}
+
+func Test_parseCache_invalid(t *testing.T) {
+	_, err := parseCache([]byte("not a cache"))
+	must.EqError(t, err, "ld.so cache has unknown format")
+
+	b := newCache([2]string{"libz.so.1", "/usr/lib/libz.so.1"})
+	_, err = parseCache(b[:cacheHeaderNew+4])
+	must.EqError(t, err, "ld.so cache is truncated")
+}
+
+func TestReadCache(t *testing.T) {
+	cache, err := ReadCache("/does/not/exist")
+	must.NoError(t, err)
+	must.MapEmpty(t, cache)
+}
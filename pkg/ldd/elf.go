@@ -0,0 +1,169 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package ldd
+
+import (
+	"debug/elf"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrNotELF is returned for programs which are not ELF executables.
+var ErrNotELF = errors.New("not an ELF executable")
+
+// defaultDirs are searched for libraries not found otherwise, after the dirs
+// of the ld.so cache.
+var defaultDirs = []string{"/lib64", "/usr/lib64", "/lib", "/usr/lib"}
+
+// Needs are the files needed to execute an ELF program, with symlinks in their
+// paths resolved. A statically linked program needs none.
+type Needs struct {
+	Interpreter string
+	Libraries   []string
+}
+
+// object is an ELF file to find the libraries of.
+type object struct {
+	path  string
+	rpath []string // inherited from the loading objects, if no runpath
+}
+
+// Resolve returns the files needed to execute the ELF program at path, i.e.
+// its PT_INTERP interpreter, and the DT_NEEDED libraries of the program and of
+// those libraries in turn, found through their DT_RPATH and DT_RUNPATH, the
+// ld.so cache, and the default library directories.
+func Resolve(path string, cache Cache) (*Needs, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		var formatErr *elf.FormatError
+		if errors.As(err, &formatErr) {
+			return nil, ErrNotELF
+		}
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	class, machine := f.Class, f.Machine
+	needs := new(Needs)
+
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_INTERP {
+			continue
+		}
+		b := make([]byte, prog.Filesz)
+		if _, err = prog.ReadAt(b, 0); err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("failed to read interpreter of %s: %w", path, err)
+		}
+		interp := strings.TrimRight(string(b), "\x00")
+		if needs.Interpreter, err = filepath.EvalSymlinks(interp); err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("interpreter %s of %s not found", interp, path)
+		}
+	}
+	_ = f.Close()
+
+	// walk the tree of needed libraries, each found once
+	seen := map[string]bool{}
+	queue := []object{{path: path}}
+	for len(queue) > 0 {
+		obj := queue[0]
+		queue = queue[1:]
+
+		needed, rpath, runpath, err := dynamic(obj.path)
+		if err != nil {
+			return nil, err
+		}
+		if len(runpath) > 0 {
+			rpath = nil // DT_RPATH is ignored when DT_RUNPATH is present
+		} else {
+			rpath = append(rpath, obj.rpath...)
+		}
+
+		for _, name := range needed {
+			lib, err := find(name, slices.Concat(rpath, runpath), cache, class, machine)
+			if err != nil {
+				return nil, fmt.Errorf("library %s needed by %s: %w", name, obj.path, err)
+			}
+			if seen[lib] {
+				continue
+			}
+			seen[lib] = true
+			needs.Libraries = append(needs.Libraries, lib)
+			queue = append(queue, object{path: lib, rpath: rpath})
+		}
+	}
+	return needs, nil
+}
+
+// dynamic returns the DT_NEEDED names and the DT_RPATH and DT_RUNPATH dirs of
+// the ELF file at path, with $ORIGIN expanded.
+func dynamic(path string) ([]string, []string, []string, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	needed, err := f.DynString(elf.DT_NEEDED)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read dynamic section of %s: %w", path, err)
+	}
+	rpath, _ := f.DynString(elf.DT_RPATH)
+	runpath, _ := f.DynString(elf.DT_RUNPATH)
+
+	origin := filepath.Dir(path)
+	dirs := func(values []string) []string {
+		var result []string
+		for _, value := range values {
+			for _, dir := range filepath.SplitList(value) {
+				dir = strings.ReplaceAll(dir, "${ORIGIN}", origin)
+				dir = strings.ReplaceAll(dir, "$ORIGIN", origin)
+				result = append(result, dir)
+			}
+		}
+		return result
+	}
+	return needed, dirs(rpath), dirs(runpath), nil
+}
+
+// find returns the resolved path of the named library for the given class and
+// machine, searching dirs, then the cache, then the default directories.
+func find(name string, dirs []string, cache Cache, class elf.Class, machine elf.Machine) (string, error) {
+	var candidates []string
+	if strings.Contains(name, "/") {
+		candidates = []string{name}
+	} else {
+		for _, dir := range dirs {
+			candidates = append(candidates, filepath.Join(dir, name))
+		}
+		candidates = append(candidates, cache[name]...)
+		for _, dir := range defaultDirs {
+			candidates = append(candidates, filepath.Join(dir, name))
+		}
+	}
+
+	for _, candidate := range candidates {
+		resolved, err := filepath.EvalSymlinks(candidate)
+		if err != nil {
+			continue
+		}
+		if matches(resolved, class, machine) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("not found")
+}
+
+// matches reports whether the file at path is an ELF file of the given class
+// and machine, as libraries of other architectures may share a name.
+func matches(path string, class elf.Class, machine elf.Machine) bool {
+	f, err := elf.Open(path)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+	return f.Class == class && f.Machine == machine
+}
@@ -0,0 +1,61 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package ldd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shoenig/test/must"
+)
+
+func TestResolve_dynamic(t *testing.T) {
+	sh, err := filepath.EvalSymlinks("/bin/sh")
+	if err != nil {
+		t.Skip("requires /bin/sh")
+	}
+
+	cache, err := ReadCache(CacheFile)
+	must.NoError(t, err)
+
+	needs, err := Resolve(sh, cache)
+	must.NoError(t, err)
+	must.StrHasPrefix(t, "/", needs.Interpreter)
+	must.True(t, strings.Contains(filepath.Base(needs.Interpreter), "ld-"))
+
+	var libc bool
+	for _, lib := range needs.Libraries {
+		libc = libc || strings.HasPrefix(filepath.Base(lib), "libc.so")
+		resolved, err := filepath.EvalSymlinks(lib)
+		must.NoError(t, err)
+		must.Eq(t, resolved, lib)
+	}
+	must.True(t, libc, must.Sprintf("libc not in %v", needs.Libraries))
+}
+
+func TestResolve_notELF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script")
+	must.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"), 0o755))
+
+	_, err := Resolve(path, Cache{})
+	must.ErrorIs(t, err, ErrNotELF)
+}
+
+func TestResolve_missingLibrary(t *testing.T) {
+	sh, err := filepath.EvalSymlinks("/bin/sh")
+	if err != nil {
+		t.Skip("requires /bin/sh")
+	}
+
+	// with no cache and no default dirs, libc cannot be found unless the
+	// program has a runpath
+	original := defaultDirs
+	defaultDirs = nil
+	t.Cleanup(func() { defaultDirs = original })
+
+	_, err = Resolve(sh, Cache{})
+	must.ErrorContains(t, err, "needed by "+sh+": not found")
+}
@@ -0,0 +1,46 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package ldd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// maxShebang is the length of the interpreter line read by the kernel.
+const maxShebang = 256
+
+// Shebang returns the interpreter and its optional argument from the "#!" line
+// of the script at path. It returns an empty interpreter if the file is not a
+// script.
+func Shebang(path string) (string, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	line, err := bufio.NewReaderSize(f, maxShebang).Peek(maxShebang)
+	if len(line) < 2 {
+		return "", "", nil
+	}
+	if !bytes.HasPrefix(line, []byte("#!")) {
+		return "", "", nil
+	}
+	if end := bytes.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	} else if err == nil {
+		return "", "", fmt.Errorf("interpreter line of %s is too long", path)
+	}
+
+	// like the kernel, everything after the interpreter is one argument
+	fields := strings.TrimSpace(string(line[2:]))
+	if i := strings.IndexAny(fields, " \t"); i >= 0 {
+		return fields[:i], strings.TrimSpace(fields[i+1:]), nil
+	}
+	return fields, "", nil
+}
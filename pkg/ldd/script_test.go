@@ -0,0 +1,41 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package ldd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shoenig/test/must"
+)
+
+func TestShebang(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		interp  string
+		arg     string
+	}{
+		{name: "sh", content: "#!/bin/sh\necho hi\n", interp: "/bin/sh"},
+		{name: "env", content: "#!/usr/bin/env python3\nprint()\n", interp: "/usr/bin/env", arg: "python3"},
+		{name: "spaces", content: "#! /bin/bash  -e -u \n", interp: "/bin/bash", arg: "-e -u"},
+		{name: "tab", content: "#!/bin/sh\t-e\n", interp: "/bin/sh", arg: "-e"},
+		{name: "no newline", content: "#!/bin/true", interp: "/bin/true"},
+		{name: "not a script", content: "\x7fELF", interp: ""},
+		{name: "empty", content: "", interp: ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "script")
+			must.NoError(t, os.WriteFile(path, []byte(tc.content), 0o755))
+
+			interp, arg, err := Shebang(path)
+			must.NoError(t, err)
+			must.Eq(t, tc.interp, interp)
+			must.Eq(t, tc.arg, arg)
+		})
+	}
+}
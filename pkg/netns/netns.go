@@ -0,0 +1,82 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build linux
+
+package netns
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/nomad/client/lib/nsutil"
+	"golang.org/x/sys/unix"
+)
+
+// Path returns the filepath of the persistent network namespace of the
+// given name.
+func Path(name string) string {
+	return filepath.Join(nsutil.NetNSRunDir, name)
+}
+
+// Create a persistent (bind-mounted) network namespace of the given name,
+// with only the loopback interface brought up. Returns the filepath of the
+// network namespace.
+func Create(name string) (string, error) {
+	ns, err := nsutil.NewNS(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to create network namespace: %w", err)
+	}
+	defer func() { _ = ns.Close() }()
+
+	if err = ns.Do(func(nsutil.NetNS) error { return loopback() }); err != nil {
+		_ = Destroy(ns.Path())
+		return "", fmt.Errorf("failed to set up loopback interface: %w", err)
+	}
+
+	return ns.Path(), nil
+}
+
+// Exists returns whether path is the mount point of a network namespace.
+func Exists(path string) bool {
+	return nsutil.IsNSorErr(path) == nil
+}
+
+// Destroy the persistent network namespace at path, if it exists. A stale
+// mount point that is no longer a network namespace is also removed.
+func Destroy(path string) error {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err := unix.Unmount(path, unix.MNT_DETACH); err != nil && !errors.Is(err, unix.EINVAL) {
+		return fmt.Errorf("failed to unmount network namespace %s: %w", path, err)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove network namespace %s: %w", path, err)
+	}
+	return nil
+}
+
+// loopback sets the UP flag on the loopback interface of the network
+// namespace of the calling thread.
+func loopback() error {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = unix.Close(fd) }()
+
+	ifr, err := unix.NewIfreq("lo")
+	if err != nil {
+		return err
+	}
+
+	if err = unix.IoctlIfreq(fd, unix.SIOCGIFFLAGS, ifr); err != nil {
+		return err
+	}
+
+	ifr.SetUint16(ifr.Uint16() | unix.IFF_UP)
+	return unix.IoctlIfreq(fd, unix.SIOCSIFFLAGS, ifr)
+}
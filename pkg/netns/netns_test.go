@@ -0,0 +1,62 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build linux
+
+package netns
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/nomad/client/lib/nsutil"
+	ctests "github.com/hashicorp/nomad/client/testutil"
+	"github.com/hashicorp/nomad/helper/uuid"
+	"github.com/shoenig/test/must"
+)
+
+func TestCreate(t *testing.T) {
+	ctests.RequireRoot(t)
+
+	name := "exec2-test-" + uuid.Short()
+	path, err := Create(name)
+	must.NoError(t, err)
+	must.Eq(t, Path(name), path)
+
+	t.Cleanup(func() { _ = Destroy(path) })
+
+	err = nsutil.WithNetNSPath(path, func(nsutil.NetNS) error {
+		ifaces, err := net.Interfaces()
+		if err != nil {
+			return err
+		}
+		must.SliceLen(t, 1, ifaces)
+		must.Eq(t, "lo", ifaces[0].Name)
+		must.True(t, ifaces[0].Flags&net.FlagUp != 0)
+		return nil
+	})
+	must.NoError(t, err)
+
+	must.True(t, Exists(path))
+	must.NoError(t, Destroy(path))
+	must.FileNotExists(t, path)
+	must.False(t, Exists(path))
+
+	// destroying a missing namespace is a no-op
+	must.NoError(t, Destroy(path))
+}
+
+func TestDestroy_stale(t *testing.T) {
+	ctests.RequireRoot(t)
+
+	// a leftover mount point which is not a network namespace
+	path := Path("exec2-test-" + uuid.Short())
+	must.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	must.NoError(t, os.WriteFile(path, nil, 0o644))
+	must.False(t, Exists(path))
+
+	must.NoError(t, Destroy(path))
+	must.FileNotExists(t, path)
+}
@@ -0,0 +1,55 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package resources
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/nomad/client/lib/idset"
+	"github.com/hashicorp/nomad/client/lib/numalib/hw"
+)
+
+// nodesDir is where the kernel lists the NUMA nodes of the machine and the
+// cpus of each node.
+const nodesDir = "/sys/devices/system/node"
+
+// MemoryNodes returns the NUMA nodes of the given cpus, in the list format of
+// cpuset.mems. It returns an empty list if the kernel does not describe the
+// NUMA topology of the machine, in which case there is nothing to restrict.
+// On a single node machine the result is simply "0".
+func MemoryNodes(cpus string) (string, error) {
+	return memoryNodes(nodesDir, cpus)
+}
+
+func memoryNodes(dir, cpus string) (string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "node[0-9]*"))
+	if err != nil || len(paths) == 0 {
+		return "", nil
+	}
+
+	want := idset.Parse[hw.CoreID](cpus)
+	nodes := idset.Empty[hw.NodeID]()
+	for _, path := range paths {
+		var id hw.NodeID
+		if _, err = fmt.Sscanf(filepath.Base(path), "node%d", &id); err != nil {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(path, "cpulist"))
+		if err != nil {
+			return "", fmt.Errorf("failed to read cpus of numa node %d: %w", id, err)
+		}
+		have := idset.Parse[hw.CoreID](strings.TrimSpace(string(b)))
+		if !have.Intersect(want).Empty() {
+			nodes.Insert(id)
+		}
+	}
+
+	if nodes.Empty() {
+		return "", fmt.Errorf("cpus %q are not on any numa node", cpus)
+	}
+	return nodes.String(), nil
+}
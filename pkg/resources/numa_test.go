@@ -0,0 +1,54 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package resources
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shoenig/test/must"
+)
+
+func writeNodes(t *testing.T, cpulists ...string) string {
+	dir := t.TempDir()
+	for i, cpulist := range cpulists {
+		node := filepath.Join(dir, "node"+string(rune('0'+i)))
+		must.NoError(t, os.Mkdir(node, 0o755))
+		must.NoError(t, os.WriteFile(filepath.Join(node, "cpulist"), []byte(cpulist+"\n"), 0o644))
+	}
+	// not a node
+	must.NoError(t, os.WriteFile(filepath.Join(dir, "possible"), []byte("0-1\n"), 0o644))
+	return dir
+}
+
+func Test_memoryNodes(t *testing.T) {
+	cases := []struct {
+		name  string
+		nodes []string
+		cpus  string
+		exp   string
+		err   string
+	}{
+		{name: "single node", nodes: []string{"0-7"}, cpus: "2-3", exp: "0"},
+		{name: "one of two", nodes: []string{"0-3", "4-7"}, cpus: "5,6", exp: "1"},
+		{name: "both of two", nodes: []string{"0-3", "4-7"}, cpus: "3-4", exp: "0-1"},
+		{name: "interleaved", nodes: []string{"0,2", "1,3", "4,6"}, cpus: "2,6", exp: "0,2"},
+		{name: "no node", nodes: []string{"0-3"}, cpus: "8", err: "not on any numa node"},
+		{name: "no topology", cpus: "0", exp: ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := writeNodes(t, tc.nodes...)
+			result, err := memoryNodes(dir, tc.cpus)
+			if tc.err != "" {
+				must.ErrorContains(t, err, tc.err)
+				return
+			}
+			must.NoError(t, err)
+			must.Eq(t, tc.exp, result)
+		})
+	}
+}
@@ -4,6 +4,7 @@
 package resources
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
@@ -22,9 +23,80 @@ type Utilization struct {
 	System          Percent
 	User            Percent
 	Percent         Percent
-	ThrottlePeriods uint64
-	ThrottleTime    uint64
+	ThrottlePeriods uint64 // periods in which the task was throttled
+	ThrottleTime    uint64 // nanoseconds the task was throttled for
 	Ticks           Percent
+
+	IO []*BlockIO
+
+	MemoryEvents MemoryEvents
+}
+
+// MemoryEvents are the cumulative counts of memory.events of a cgroup.
+type MemoryEvents struct {
+	Low     uint64 // times usage was below memory.low yet reclaimed
+	High    uint64 // times usage exceeded memory.high and was throttled
+	Max     uint64 // times usage was about to exceed memory.max
+	OOM     uint64 // times usage reached memory.max and allocation failed
+	OOMKill uint64 // processes killed by the OOM killer
+}
+
+// BlockIO is the io.stat accounting of one block device. The counters are
+// cumulative; the rates are per second since the previous sample.
+type BlockIO struct {
+	Device string
+
+	ReadBytes    uint64
+	WriteBytes   uint64
+	ReadIOs      uint64
+	WriteIOs     uint64
+	DiscardBytes uint64
+
+	ReadBytesRate    float64
+	WriteBytesRate   float64
+	ReadIOsRate      float64
+	WriteIOsRate     float64
+	DiscardBytesRate float64
+}
+
+// TrackIO computes block io rates between successive samples of io.stat.
+type TrackIO struct {
+	prevTime time.Time
+	prev     map[string]BlockIO
+}
+
+// Rates sets the rate fields of each element of current, relative to the
+// counters of the same device in the previous sample.
+func (t *TrackIO) Rates(current []*BlockIO) {
+	now := time.Now()
+	elapsed := now.Sub(t.prevTime).Seconds()
+
+	if t.prev != nil && elapsed > 0 {
+		for _, dev := range current {
+			prev, exists := t.prev[dev.Device]
+			if !exists {
+				continue
+			}
+			dev.ReadBytesRate = rate(prev.ReadBytes, dev.ReadBytes, elapsed)
+			dev.WriteBytesRate = rate(prev.WriteBytes, dev.WriteBytes, elapsed)
+			dev.ReadIOsRate = rate(prev.ReadIOs, dev.ReadIOs, elapsed)
+			dev.WriteIOsRate = rate(prev.WriteIOs, dev.WriteIOs, elapsed)
+			dev.DiscardBytesRate = rate(prev.DiscardBytes, dev.DiscardBytes, elapsed)
+		}
+	}
+
+	t.prev = make(map[string]BlockIO, len(current))
+	for _, dev := range current {
+		t.prev[dev.Device] = *dev
+	}
+	t.prevTime = now
+}
+
+func rate(v1, v2 uint64, elapsed float64) float64 {
+	if v2 <= v1 {
+		return 0.0
+	}
+	return float64(v2-v1) / elapsed
 }
 
 type TrackCPU struct {
@@ -79,7 +151,10 @@ var (
 )
 
 func SetSpecs(compute cpustats.Compute) {
-	perCore := uint64(compute.TotalCompute) / uint64(compute.NumCores)
+	var perCore uint64
+	if compute.NumCores > 0 {
+		perCore = uint64(compute.TotalCompute) / uint64(compute.NumCores)
+	}
 
 	lock.Lock()
 	specs.MHz = perCore
@@ -95,10 +170,16 @@ func GetSpecs() Specs {
 	return s
 }
 
-// Bandwidth computes the CPU bandwidth given a mhz value from task config.
-// We assume the bandwidth per-core base is 100_000 which is the default.
-func Bandwidth(mhz uint64) (uint64, error) {
+// DefaultPeriod is the default period of cpu.max in microseconds.
+const DefaultPeriod = 100000
+
+// Bandwidth computes the CPU bandwidth given a mhz value from task config, as
+// the microseconds of CPU time the task may use within each period.
+func Bandwidth(mhz, period uint64) (uint64, error) {
 	speed := GetSpecs().MHz
-	v := (mhz * 100000) / speed
+	if speed == 0 {
+		return 0, fmt.Errorf("cpu speed of node is unknown")
+	}
+	v := (mhz * period) / speed
 	return v, nil
 }
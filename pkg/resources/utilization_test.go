@@ -28,3 +28,25 @@ func TestTrackCPU_Percent(t *testing.T) {
 	must.Between(t, 2, system, 3)
 	must.Between(t, 3, total, 4)
 }
+
+func TestTrackIO_Rates(t *testing.T) {
+	tio := new(TrackIO)
+
+	// initial -> all zeros
+	first := []*BlockIO{{Device: "sda", ReadBytes: 1000, WriteBytes: 1000}}
+	tio.Rates(first)
+	must.Eq(t, 0, first[0].ReadBytesRate)
+	must.Eq(t, 0, first[0].WriteBytesRate)
+
+	time.Sleep(10 * time.Millisecond)
+
+	// we did some reading but no writing
+	second := []*BlockIO{
+		{Device: "sda", ReadBytes: 2000, WriteBytes: 1000},
+		{Device: "sdb", ReadBytes: 5000},
+	}
+	tio.Rates(second)
+	must.Positive(t, second[0].ReadBytesRate)
+	must.Eq(t, 0, second[0].WriteBytesRate)
+	must.Eq(t, 0, second[1].ReadBytesRate) // new device has no rate yet
+}
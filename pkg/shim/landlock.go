@@ -0,0 +1,199 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package shim
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/landlock-lsm/go-landlock/landlock"
+	ll "github.com/landlock-lsm/go-landlock/landlock/syscall"
+)
+
+// Access is a set of landlock filesystem access rights.
+type Access = landlock.AccessFSSet
+
+// AccessExecute is the right to execute a file.
+const AccessExecute Access = ll.AccessFSExecute
+
+const (
+	accessRefer    Access = ll.AccessFSRefer
+	accessTruncate Access = ll.AccessFSTruncate
+	accessIoctlDev Access = ll.AccessFSIoctlDev
+
+	// accessFile are the rights which apply to files, rather than to the
+	// entries of directories
+	accessFile = AccessExecute | ll.AccessFSWriteFile |
+		ll.AccessFSReadFile | accessTruncate | accessIoctlDev
+)
+
+// rights are the names of the landlock access rights in the extended mode
+// grammar.
+var rights = map[string]Access{
+	"execute":     AccessExecute,
+	"write-file":  ll.AccessFSWriteFile,
+	"read-file":   ll.AccessFSReadFile,
+	"read-dir":    ll.AccessFSReadDir,
+	"remove-dir":  ll.AccessFSRemoveDir,
+	"remove-file": ll.AccessFSRemoveFile,
+	"make-char":   ll.AccessFSMakeChar,
+	"make-dir":    ll.AccessFSMakeDir,
+	"make-reg":    ll.AccessFSMakeReg,
+	"make-sock":   ll.AccessFSMakeSock,
+	"make-fifo":   ll.AccessFSMakeFifo,
+	"make-block":  ll.AccessFSMakeBlock,
+	"make-sym":    ll.AccessFSMakeSym,
+	"refer":       accessRefer,
+	"truncate":    accessTruncate,
+	"ioctl-dev":   accessIoctlDev,
+}
+
+// letters are the rights of each letter of the original mode grammar.
+var letters = map[rune]Access{
+	'r': rights["read-file"] | rights["read-dir"],
+	'w': rights["write-file"] | accessTruncate,
+	'x': AccessExecute,
+	'c': rights["make-reg"] | rights["make-sock"] | rights["make-fifo"] |
+		rights["make-block"] | rights["make-sym"] | rights["make-dir"] |
+		rights["remove-file"] | rights["remove-dir"] | accessRefer,
+}
+
+// introduced maps the rights added after the first landlock ABI to the ABI
+// version which introduced them.
+var introduced = map[Access]int{
+	accessRefer:    2,
+	accessTruncate: 3,
+	accessIoctlDev: 5,
+}
+
+// ParseMode parses the mode of an unveil entry, which is a comma separated
+// list of terms, each being either letters of "rwxc" or the name of a landlock
+// access right, e.g. "r,make-sock". The rights of a directory apply to the
+// files and directories beneath it.
+func ParseMode(mode string) (Access, error) {
+	var access Access
+	for _, term := range strings.Split(mode, ",") {
+		if right, ok := rights[term]; ok {
+			access |= right
+			continue
+		}
+		if term == "" || strings.Trim(term, "rwxc") != "" {
+			return 0, fmt.Errorf("mode %q has unknown right %q", mode, term)
+		}
+		for _, letter := range term {
+			access |= letters[letter]
+		}
+	}
+	return access, nil
+}
+
+// rule is a path unveiled with the access rights of its mode.
+type rule struct {
+	path   string
+	access Access
+	dir    bool
+}
+
+// ABI returns the version of the landlock ABI of the kernel, or an error if
+// landlock is not supported or not enabled.
+func ABI() (int, error) {
+	return ll.LandlockGetABIVersion()
+}
+
+// supported returns the rights the given landlock ABI can restrict.
+func supported(abi int) Access {
+	var access Access
+	for _, right := range rights {
+		if abi >= max(introduced[right], 1) {
+			access |= right
+		}
+	}
+	return access
+}
+
+// restricted returns the rights restricted for a task whose rules grant the
+// requested rights. The ioctl rights of devices are only restricted if a rule
+// grants them, which keeps tasks able to use e.g. terminals unless told
+// otherwise.
+func restricted(requested Access) Access {
+	var access Access
+	for _, right := range rights {
+		access |= right
+	}
+	if requested&accessIoctlDev == 0 {
+		access &^= accessIoctlDev
+	}
+	return access
+}
+
+// unenforced returns the rights of access which the given landlock ABI cannot
+// restrict. Refer is never missing, as before ABI 2 introduced the right the
+// kernel denies renaming and linking files across directories outright.
+func unenforced(abi int, access Access) Access {
+	missing := access &^ supported(abi)
+	if abi < introduced[accessRefer] {
+		missing &^= accessRefer
+	}
+	return missing
+}
+
+// requested returns the rights granted by any of the rules.
+func requested(rules []rule) Access {
+	var access Access
+	for _, r := range rules {
+		access |= r.access
+	}
+	return access
+}
+
+// Unenforced returns the rights which the given landlock ABI cannot restrict
+// for a task with the given unveil elements, which are left unrestricted with
+// best-effort enforcement.
+func Unenforced(abi int, defaults bool, elements []string) Access {
+	var access Access
+	for _, element := range withDefaults(defaults, elements) {
+		element = strings.TrimPrefix(element, Optional)
+		if idx := strings.LastIndex(element, ":"); idx >= 0 {
+			mode, _ := ParseMode(element[:idx])
+			access |= mode
+		}
+	}
+	return unenforced(abi, restricted(access))
+}
+
+// restrict applies a landlock ruleset allowing only the given rules to every
+// thread of the shim, and so to the task it starts. Unless best effort, the
+// kernel must support restricting every right.
+func restrict(rules []rule, bestEffort bool) error {
+	abi, err := ABI()
+	if err != nil {
+		return fmt.Errorf("landlock not available: %w", err)
+	}
+	if missing := unenforced(abi, restricted(requested(rules))); missing != 0 && !bestEffort {
+		return fmt.Errorf("landlock ABI %d cannot restrict %s", abi, missing)
+	}
+	access := restricted(requested(rules)) & supported(abi)
+
+	config, err := landlock.NewConfig(access)
+	if err != nil {
+		return fmt.Errorf("failed to create landlock config: %w", err)
+	}
+
+	paths := make([]landlock.Rule, 0, len(rules))
+	for _, r := range rules {
+		allowed := r.access & access
+		if !r.dir {
+			allowed &= accessFile
+		}
+		if allowed == 0 {
+			continue // nothing to allow beyond what is not restricted
+		}
+		paths = append(paths, landlock.PathAccess(allowed, r.path))
+	}
+
+	if err = config.RestrictPaths(paths...); err != nil {
+		return fmt.Errorf("failed to enforce landlock ruleset: %w", err)
+	}
+	return nil
+}
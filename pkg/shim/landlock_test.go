@@ -0,0 +1,176 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package shim
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	ctests "github.com/hashicorp/nomad/client/testutil"
+	"github.com/shoenig/test/must"
+	"golang.org/x/sys/unix"
+)
+
+func TestParseMode(t *testing.T) {
+	cases := []struct {
+		name   string
+		mode   string
+		exp    Access
+		expErr string
+	}{
+		{
+			name: "read",
+			mode: "r",
+			exp:  unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_READ_DIR,
+		},
+		{
+			name: "write",
+			mode: "w",
+			exp:  unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_TRUNCATE,
+		},
+		{
+			name: "letters",
+			mode: "rx",
+			exp:  unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_READ_DIR | unix.LANDLOCK_ACCESS_FS_EXECUTE,
+		},
+		{
+			name: "create",
+			mode: "c",
+			exp: unix.LANDLOCK_ACCESS_FS_MAKE_REG | unix.LANDLOCK_ACCESS_FS_MAKE_SOCK |
+				unix.LANDLOCK_ACCESS_FS_MAKE_FIFO | unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK |
+				unix.LANDLOCK_ACCESS_FS_MAKE_SYM | unix.LANDLOCK_ACCESS_FS_MAKE_DIR |
+				unix.LANDLOCK_ACCESS_FS_REMOVE_FILE | unix.LANDLOCK_ACCESS_FS_REMOVE_DIR |
+				unix.LANDLOCK_ACCESS_FS_REFER,
+		},
+		{
+			name: "socket",
+			mode: "make-sock",
+			exp:  unix.LANDLOCK_ACCESS_FS_MAKE_SOCK,
+		},
+		{
+			name: "append only",
+			mode: "read-dir,write-file,make-reg",
+			exp:  unix.LANDLOCK_ACCESS_FS_READ_DIR | unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_MAKE_REG,
+		},
+		{
+			name: "letters and rights",
+			mode: "x,read-file,ioctl-dev",
+			exp:  unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_IOCTL_DEV,
+		},
+		{name: "empty", mode: "", expErr: `mode "" has unknown right ""`},
+		{name: "empty term", mode: "r,", expErr: `mode "r," has unknown right ""`},
+		{name: "unknown letter", mode: "rq", expErr: `mode "rq" has unknown right "rq"`},
+		{name: "unknown right", mode: "r,delete", expErr: `mode "r,delete" has unknown right "delete"`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			access, err := ParseMode(tc.mode)
+			if tc.expErr != "" {
+				must.EqError(t, err, tc.expErr)
+				return
+			}
+			must.NoError(t, err)
+			must.Eq(t, tc.exp, access)
+		})
+	}
+}
+
+func Test_supported(t *testing.T) {
+	base := Access(1<<13 - 1) // execute through make-sym
+
+	must.Eq(t, base, supported(1))
+	must.Eq(t, base|accessRefer, supported(2))
+	must.Eq(t, base|accessRefer|accessTruncate, supported(4))
+	must.Eq(t, base|accessRefer|accessTruncate|accessIoctlDev, supported(5))
+}
+
+func Test_restricted(t *testing.T) {
+	all := supported(5)
+
+	// device ioctls are restricted only if some rule allows them
+	must.Eq(t, all&^accessIoctlDev, restricted(AccessExecute))
+	must.Eq(t, all, restricted(accessIoctlDev))
+}
+
+func Test_unenforced(t *testing.T) {
+	all := restricted(AccessExecute)
+
+	// the first ABI denies refer outright, so it is not left unrestricted
+	must.Eq(t, accessTruncate, unenforced(1, all))
+	must.Eq(t, accessTruncate, unenforced(2, all))
+	must.Eq(t, 0, unenforced(3, all))
+	must.Eq(t, accessIoctlDev, unenforced(4, all|accessIoctlDev))
+}
+
+func TestUnenforced(t *testing.T) {
+	must.Eq(t, 0, Unenforced(5, true, []string{"rwc:/srv"}))
+	must.Eq(t, 0, Unenforced(3, true, []string{"rwc:/srv"}))
+	must.Eq(t, accessTruncate, Unenforced(2, false, []string{"r:/srv"}))
+	must.Eq(t, accessTruncate, Unenforced(1, false, nil))
+	must.Eq(t, accessIoctlDev, Unenforced(4, false, []string{"?r:/srv", "!/srv/secret", "rw,ioctl-dev:/dev/dri"}))
+}
+
+// restrictDirEnv names the directory whose files the helper process of
+// Test_restrict locks itself down to.
+const restrictDirEnv = "EXEC2_TEST_RESTRICT_DIR"
+
+func Test_restrict(t *testing.T) {
+	ctests.RequireRoot(t)
+	if _, err := ABI(); err != nil {
+		t.Skipf("landlock not available: %v", err)
+	}
+
+	dir := t.TempDir()
+	must.NoError(t, os.WriteFile(filepath.Join(dir, "readable"), []byte("hi"), 0o644))
+	must.NoError(t, os.WriteFile(filepath.Join(dir, "hidden"), []byte("hi"), 0o644))
+	must.NoError(t, os.Mkdir(filepath.Join(dir, "scratch"), 0o755))
+
+	// a process cannot be unrestricted, so lock down a copy of the test
+	cmd := exec.Command(os.Args[0], "-test.run=^Test_restrict_helper$", "-test.v")
+	cmd.Env = append(os.Environ(), restrictDirEnv+"="+dir)
+	output, err := cmd.CombinedOutput()
+	must.NoError(t, err, must.Sprint(string(output)))
+	must.StrContains(t, string(output), "--- PASS: Test_restrict_helper")
+}
+
+func Test_restrict_helper(t *testing.T) {
+	dir := os.Getenv(restrictDirEnv)
+	if dir == "" {
+		t.Skip("run by Test_restrict")
+	}
+
+	read, err := ParseMode("r")
+	must.NoError(t, err)
+	create, err := ParseMode("rwc")
+	must.NoError(t, err)
+
+	rules := []rule{
+		{path: filepath.Join(dir, "readable"), access: read},
+		{path: filepath.Join(dir, "scratch"), access: create, dir: true},
+	}
+	must.NoError(t, restrict(rules, true))
+
+	// unveiled with read access
+	_, err = os.ReadFile(filepath.Join(dir, "readable"))
+	must.NoError(t, err)
+	err = os.WriteFile(filepath.Join(dir, "readable"), []byte("bye"), 0o644)
+	must.ErrorIs(t, err, fs.ErrPermission)
+
+	// not unveiled
+	_, err = os.ReadFile(filepath.Join(dir, "hidden"))
+	must.ErrorIs(t, err, fs.ErrPermission)
+	_, err = os.ReadDir(dir)
+	must.ErrorIs(t, err, fs.ErrPermission)
+
+	// unveiled with create access, which applies beneath the directory
+	must.NoError(t, os.Mkdir(filepath.Join(dir, "scratch", "sub"), 0o755))
+	must.NoError(t, os.WriteFile(filepath.Join(dir, "scratch", "sub", "file"), []byte("hi"), 0o644))
+	entries, err := os.ReadDir(filepath.Join(dir, "scratch"))
+	must.NoError(t, err)
+	must.Len(t, 1, entries)
+}
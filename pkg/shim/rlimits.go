@@ -0,0 +1,112 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package shim
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// Unlimited is the value of a resource limit without limit.
+const Unlimited = uint64(math.MaxUint64)
+
+// Rlimit is a resource limit of the task, applied with setrlimit(2).
+type Rlimit struct {
+	Name     string `json:"name"`
+	Resource int    `json:"resource"`
+	Soft     uint64 `json:"soft"`
+	Hard     uint64 `json:"hard"`
+}
+
+// rlimitResources maps the names of resource limits to their resource, as in
+// the RLIMIT_* constants of setrlimit(2).
+var rlimitResources = map[string]int{
+	"as":         unix.RLIMIT_AS,
+	"core":       unix.RLIMIT_CORE,
+	"cpu":        unix.RLIMIT_CPU,
+	"data":       unix.RLIMIT_DATA,
+	"fsize":      unix.RLIMIT_FSIZE,
+	"locks":      unix.RLIMIT_LOCKS,
+	"memlock":    unix.RLIMIT_MEMLOCK,
+	"msgqueue":   unix.RLIMIT_MSGQUEUE,
+	"nice":       unix.RLIMIT_NICE,
+	"nofile":     unix.RLIMIT_NOFILE,
+	"nproc":      unix.RLIMIT_NPROC,
+	"rss":        unix.RLIMIT_RSS,
+	"rtprio":     unix.RLIMIT_RTPRIO,
+	"rttime":     unix.RLIMIT_RTTIME,
+	"sigpending": unix.RLIMIT_SIGPENDING,
+	"stack":      unix.RLIMIT_STACK,
+}
+
+// ParseRlimit parses the value of the named resource limit, in the form
+// "<soft>[:<hard>]", where each limit is a number or "unlimited". If only one
+// limit is given it is used as both the soft and hard limit.
+func ParseRlimit(name, value string) (Rlimit, error) {
+	resource, ok := rlimitResources[name]
+	if !ok {
+		return Rlimit{}, fmt.Errorf("rlimit %q is not a known resource limit", name)
+	}
+
+	softS, hardS, split := strings.Cut(value, ":")
+	if !split {
+		hardS = softS
+	}
+
+	soft, err := parseLimit(softS)
+	if err != nil {
+		return Rlimit{}, fmt.Errorf("rlimit %s has invalid soft limit: %w", name, err)
+	}
+
+	hard, err := parseLimit(hardS)
+	if err != nil {
+		return Rlimit{}, fmt.Errorf("rlimit %s has invalid hard limit: %w", name, err)
+	}
+
+	if soft > hard {
+		return Rlimit{}, fmt.Errorf("rlimit %s soft limit must not exceed hard limit", name)
+	}
+
+	return Rlimit{Name: name, Resource: resource, Soft: soft, Hard: hard}, nil
+}
+
+// InheritedRlimit returns the named resource limit of the calling process,
+// which the task inherits unless the limit is set.
+func InheritedRlimit(name string) (Rlimit, error) {
+	resource, ok := rlimitResources[name]
+	if !ok {
+		return Rlimit{}, fmt.Errorf("rlimit %q is not a known resource limit", name)
+	}
+	var limit unix.Rlimit
+	if err := unix.Getrlimit(resource, &limit); err != nil {
+		return Rlimit{}, fmt.Errorf("failed to get rlimit %s: %w", name, err)
+	}
+	return Rlimit{Name: name, Resource: resource, Soft: limit.Cur, Hard: limit.Max}, nil
+}
+
+func parseLimit(s string) (uint64, error) {
+	if s == "unlimited" {
+		return Unlimited, nil
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number or \"unlimited\"", s)
+	}
+	return v, nil
+}
+
+// String returns the limit in the form it is parsed from.
+func (r Rlimit) String() string {
+	format := func(v uint64) string {
+		if v == Unlimited {
+			return "unlimited"
+		}
+		return strconv.FormatUint(v, 10)
+	}
+	return format(r.Soft) + ":" + format(r.Hard)
+}
@@ -0,0 +1,84 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package shim
+
+import (
+	"testing"
+
+	"github.com/shoenig/test/must"
+	"golang.org/x/sys/unix"
+)
+
+func TestParseRlimit(t *testing.T) {
+	cases := []struct {
+		name   string
+		value  string
+		exp    Rlimit
+		expErr string
+	}{
+		{
+			name:  "nofile",
+			value: "1024:65536",
+			exp:   Rlimit{Name: "nofile", Resource: unix.RLIMIT_NOFILE, Soft: 1024, Hard: 65536},
+		},
+		{
+			name:  "core",
+			value: "0",
+			exp:   Rlimit{Name: "core", Resource: unix.RLIMIT_CORE, Soft: 0, Hard: 0},
+		},
+		{
+			name:  "cpu",
+			value: "3600:unlimited",
+			exp:   Rlimit{Name: "cpu", Resource: unix.RLIMIT_CPU, Soft: 3600, Hard: Unlimited},
+		},
+		{
+			name:   "bogus",
+			value:  "1",
+			expErr: `rlimit "bogus" is not a known resource limit`,
+		},
+		{
+			name:   "nproc",
+			value:  "many",
+			expErr: `rlimit nproc has invalid soft limit: "many" is not a number or "unlimited"`,
+		},
+		{
+			name:   "nproc",
+			value:  "10:-1",
+			expErr: `rlimit nproc has invalid hard limit: "-1" is not a number or "unlimited"`,
+		},
+		{
+			name:   "nofile",
+			value:  "unlimited:1024",
+			expErr: "rlimit nofile soft limit must not exceed hard limit",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name+"="+tc.value, func(t *testing.T) {
+			result, err := ParseRlimit(tc.name, tc.value)
+			if tc.expErr != "" {
+				must.EqError(t, err, tc.expErr)
+				return
+			}
+			must.NoError(t, err)
+			must.Eq(t, tc.exp, result)
+		})
+	}
+}
+
+func TestRlimit_String(t *testing.T) {
+	must.Eq(t, "1024:unlimited", Rlimit{Soft: 1024, Hard: Unlimited}.String())
+}
+
+func TestInheritedRlimit(t *testing.T) {
+	var expected unix.Rlimit
+	must.NoError(t, unix.Getrlimit(unix.RLIMIT_NOFILE, &expected))
+
+	limit, err := InheritedRlimit("nofile")
+	must.NoError(t, err)
+	must.Eq(t, Rlimit{Name: "nofile", Resource: unix.RLIMIT_NOFILE, Soft: expected.Cur, Hard: expected.Max}, limit)
+
+	_, err = InheritedRlimit("bogus")
+	must.EqError(t, err, `rlimit "bogus" is not a known resource limit`)
+}
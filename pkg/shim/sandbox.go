@@ -4,8 +4,11 @@
 package shim
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/shoenig/go-landlock"
@@ -36,50 +39,203 @@ func split(args []string) ([]string, []string) {
 	return paths, commands
 }
 
-func lockdown(defaults bool, elements []string) error {
-	paths, err := convert(elements)
+// DefaultBinDirs are the directories of executables unveiled with mode "rx"
+// along with the other default paths.
+var DefaultBinDirs = []string{"/bin", "/usr/bin", "/usr/local/bin"}
+
+// defaultPaths returns the shared libraries, standard I/O devices, and DNS and
+// certificate files of go-landlock, unveiled along with DefaultBinDirs. These
+// are the paths which existed when the shim started, each in the "mode:path"
+// form of an unveil entry, and are optional as preparing the sandbox may have
+// hidden some of them since.
+func defaultPaths() []string {
+	locker := landlock.New(landlock.Shared(), landlock.Stdio(), landlock.DNS(), landlock.Certs())
+	elements := strings.Fields(strings.Trim(locker.String(), "[]"))
+	for i, element := range elements {
+		elements[i] = Optional + element
+	}
+	return elements
+}
+
+// withDefaults returns the unveil elements of a task, preceded by the default
+// paths if enabled.
+func withDefaults(defaults bool, elements []string) []string {
+	if !defaults {
+		return elements
+	}
+	elements = slices.Concat(defaultPaths(), elements)
+	for _, dir := range DefaultBinDirs {
+		elements = append(elements, Optional+"rx:"+dir)
+	}
+	return elements
+}
+
+func lockdown(defaults bool, elements []string, bestEffort bool) error {
+	rules, err := convert(withDefaults(defaults, elements))
 	if err != nil {
 		return err
 	}
 
-	if defaults {
-		paths = append(paths, landlock.Shared())
-		paths = append(paths, landlock.Stdio())
-		paths = append(paths, landlock.DNS())
-		paths = append(paths, landlock.Certs())
-		paths = append(paths,
-			landlock.Dir("/bin", "rx"),
-			landlock.Dir("/usr/bin", "rx"),
-			landlock.Dir("/usr/local/bin", "rx"),
-		)
-	}
+	return restrict(rules, bestEffort)
+}
+
+// Expanded returns the number of paths unveiled for the given elements once
+// glob patterns and exclusions are expanded, as they would be by the shim.
+func Expanded(defaults bool, elements []string) (int, error) {
+	rules, err := convert(withDefaults(defaults, elements))
+	return len(rules), err
+}
+
+// Optional is the marker prefixing an unveil entry whose path may not exist,
+// e.g. "?r:/etc/java-17-openjdk", in which case the entry is skipped.
+const Optional = "?"
+
+// Exclude is the marker of an unveil entry excluding a path from the paths
+// unveiled by other entries, e.g. "!/etc/ssl/private". It has no mode.
+const Exclude = "!"
 
-	return landlock.New(paths...).Lock(landlock.Mandatory)
+// IsGlob reports whether the path of an unveil entry is a glob pattern, which
+// is expanded to the paths it matches when the task is launched.
+func IsGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
 }
 
-func convert(elements []string) ([]*landlock.Path, error) {
-	paths := make([]*landlock.Path, 0, len(elements))
+func convert(elements []string) ([]rule, error) {
+	rules := make([]rule, 0, len(elements))
+	var excluded []string
 
 	for _, path := range elements {
+		if pattern, ok := strings.CutPrefix(path, Exclude); ok {
+			// excluding a path which does not exist has no effect
+			matches, err := expand(pattern, true)
+			if err != nil {
+				return nil, err
+			}
+			for _, match := range matches {
+				if resolved, err := filepath.EvalSymlinks(match); err == nil {
+					excluded = append(excluded, resolved)
+				}
+			}
+			continue
+		}
+
+		optional := strings.HasPrefix(path, Optional)
+		path = strings.TrimPrefix(path, Optional)
+
 		idx := strings.LastIndex(path, ":")
 		if idx == -1 {
 			return nil, fmt.Errorf("path %q does not contain mode prefix", path)
 		}
 
 		mode := path[0:idx]
-		filepath := path[idx+1:]
+		target := path[idx+1:]
+
+		access, err := ParseMode(mode)
+		if err != nil {
+			return nil, err
+		}
 
-		info, err := os.Stat(filepath)
+		matches, err := expand(target, optional)
 		if err != nil {
-			return nil, fmt.Errorf("failed to stat unveil path: %w", err)
+			return nil, err
 		}
 
-		if info.IsDir() {
-			paths = append(paths, landlock.Dir(filepath, mode))
-		} else {
-			paths = append(paths, landlock.File(filepath, mode))
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			switch {
+			case optional && errors.Is(err, os.ErrNotExist):
+				continue
+			case err != nil:
+				return nil, fmt.Errorf("failed to stat unveil path: %w", err)
+			}
+
+			rules = append(rules, rule{path: match, access: access, dir: info.IsDir()})
 		}
 	}
 
-	return paths, nil
+	if len(excluded) > 0 {
+		return exclude(rules, excluded)
+	}
+	return rules, nil
+}
+
+// exclude returns the rules with the excluded paths carved out of them. The
+// rule of a directory containing an excluded path is replaced by rules for
+// each of its entries other than the excluded path, recursively, and rules
+// for excluded paths or paths within them are dropped. Paths are compared
+// with symlinks resolved, as landlock applies to the resolved path.
+func exclude(rules []rule, excluded []string) ([]rule, error) {
+	result := make([]rule, 0, len(rules))
+	carved := make(map[rule]bool)
+	for _, r := range rules {
+		resolved, err := filepath.EvalSymlinks(r.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve unveil path: %w", err)
+		}
+		r.path = resolved
+		if result, err = carve(result, r, excluded, carved); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// carve appends r to rules, or the rules of the entries of r if it contains
+// an excluded path. Each directory is carved once for each access, as a
+// directory may contain a symlink to itself.
+func carve(rules []rule, r rule, excluded []string, carved map[rule]bool) ([]rule, error) {
+	for _, path := range excluded {
+		if within(r.path, path) {
+			return rules, nil
+		}
+	}
+	if !r.dir || !slices.ContainsFunc(excluded, func(path string) bool { return within(path, r.path) }) {
+		return append(rules, r), nil
+	}
+	if carved[r] {
+		return rules, nil
+	}
+	carved[r] = true
+
+	entries, err := os.ReadDir(r.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read unveil path: %w", err)
+	}
+	for _, entry := range entries {
+		path, err := filepath.EvalSymlinks(filepath.Join(r.path, entry.Name()))
+		if err != nil {
+			continue // a dangling symlink
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if rules, err = carve(rules, rule{path: path, access: r.access, dir: info.IsDir()}, excluded, carved); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// within reports whether path is dir or a path beneath dir.
+func within(path, dir string) bool {
+	return dir == "/" || path == dir || strings.HasPrefix(path, dir+"/")
+}
+
+// expand returns the paths matched by the path of an unveil entry, which is
+// the path itself unless it is a glob pattern. A pattern must match a path
+// unless the entry is optional.
+func expand(path string, optional bool) ([]string, error) {
+	if !IsGlob(path) {
+		return []string{path}, nil
+	}
+
+	matches, err := filepath.Glob(path)
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("invalid unveil pattern %q: %w", path, err)
+	case len(matches) == 0 && !optional:
+		return nil, fmt.Errorf("unveil pattern %q matches no paths", path)
+	}
+	return matches, nil
 }
@@ -4,9 +4,12 @@
 package shim
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/shoenig/test/must"
+	"golang.org/x/sys/unix"
 )
 
 func Test_split(t *testing.T) {
@@ -38,3 +41,184 @@ func Test_split(t *testing.T) {
 		})
 	}
 }
+
+func Test_convert(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"jvm/a/lib", "jvm/b/lib", "jvm/c"} {
+		must.NoError(t, os.MkdirAll(filepath.Join(dir, sub), 0o755))
+	}
+	file := filepath.Join(dir, "jvm", "release")
+	r := Access(unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_READ_DIR)
+	rwc, err := ParseMode("rwc")
+	must.NoError(t, err)
+	must.NoError(t, os.WriteFile(file, []byte("17\n"), 0o644))
+
+	cases := []struct {
+		name     string
+		elements []string
+		exp      []rule
+		expErr   string
+	}{
+		{
+			name:     "dir and file",
+			elements: []string{"rwc:" + dir, "r:" + file},
+			exp:      []rule{{path: dir, access: rwc, dir: true}, {path: file, access: r}},
+		},
+		{
+			name:     "missing",
+			elements: []string{"r:" + dir + "/missing"},
+			expErr:   "failed to stat unveil path: stat " + dir + "/missing: no such file or directory",
+		},
+		{
+			name:     "optional missing",
+			elements: []string{"?r:" + dir + "/missing", "?r:" + file},
+			exp:      []rule{{path: file, access: r}},
+		},
+		{
+			name:     "glob",
+			elements: []string{"rx:" + dir + "/jvm/*/lib"},
+			exp: []rule{
+				{path: dir + "/jvm/a/lib", access: r | AccessExecute, dir: true},
+				{path: dir + "/jvm/b/lib", access: r | AccessExecute, dir: true},
+			},
+		},
+		{
+			name:     "glob no matches",
+			elements: []string{"r:" + dir + "/jdk-*"},
+			expErr:   `unveil pattern "` + dir + `/jdk-*" matches no paths`,
+		},
+		{
+			name:     "optional glob no matches",
+			elements: []string{"?r:" + dir + "/jdk-*"},
+			exp:      []rule{},
+		},
+		{
+			name:     "extended mode",
+			elements: []string{"make-sock,r:" + dir},
+			exp:      []rule{{path: dir, access: r | unix.LANDLOCK_ACCESS_FS_MAKE_SOCK, dir: true}},
+		},
+		{
+			name:     "bad mode",
+			elements: []string{"rz:" + dir},
+			expErr:   `mode "rz" has unknown right "rz"`,
+		},
+		{
+			name:     "bad glob",
+			elements: []string{"r:" + dir + "/[a"},
+			expErr:   `invalid unveil pattern "` + dir + `/[a": syntax error in pattern`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			paths, err := convert(tc.elements)
+			if tc.expErr != "" {
+				must.EqError(t, err, tc.expErr)
+				return
+			}
+			must.NoError(t, err)
+			must.Eq(t, tc.exp, paths)
+		})
+	}
+}
+
+func Test_convert_exclude(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"etc/ssl/certs", "etc/ssl/private", "etc/default"} {
+		must.NoError(t, os.MkdirAll(filepath.Join(dir, sub), 0o755))
+	}
+	for _, file := range []string{"etc/hosts", "etc/shadow", "etc/ssl/openssl.cnf"} {
+		must.NoError(t, os.WriteFile(filepath.Join(dir, file), nil, 0o644))
+	}
+	// links to an excluded path, and to the directory itself
+	must.NoError(t, os.Symlink("ssl/private", filepath.Join(dir, "etc", "private")))
+	must.NoError(t, os.Symlink(".", filepath.Join(dir, "etc", "self")))
+
+	r := Access(unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_READ_DIR)
+	etc := filepath.Join(dir, "etc")
+
+	cases := []struct {
+		name     string
+		elements []string
+		exp      []rule
+	}{
+		{
+			name:     "no exclusions",
+			elements: []string{"r:" + etc},
+			exp:      []rule{{path: etc, access: r, dir: true}},
+		},
+		{
+			name:     "exclude file",
+			elements: []string{"r:" + etc, "!" + etc + "/shadow"},
+			exp: []rule{
+				{path: etc + "/default", access: r, dir: true},
+				{path: etc + "/hosts", access: r},
+				{path: etc + "/ssl/private", access: r, dir: true}, // through the link
+				{path: etc + "/ssl", access: r, dir: true},
+			},
+		},
+		{
+			name:     "exclude nested dir",
+			elements: []string{"!" + etc + "/ssl/private", "r:" + etc},
+			exp: []rule{
+				{path: etc + "/default", access: r, dir: true},
+				{path: etc + "/hosts", access: r},
+				{path: etc + "/shadow", access: r},
+				{path: etc + "/ssl/certs", access: r, dir: true},
+				{path: etc + "/ssl/openssl.cnf", access: r},
+			},
+		},
+		{
+			name:     "exclude glob",
+			elements: []string{"r:" + etc + "/ssl", "!" + etc + "/ssl/*.cnf"},
+			exp: []rule{
+				{path: etc + "/ssl/certs", access: r, dir: true},
+				{path: etc + "/ssl/private", access: r, dir: true},
+			},
+		},
+		{
+			name:     "grant within excluded",
+			elements: []string{"r:" + etc + "/ssl/private", "!" + etc + "/ssl"},
+			exp:      []rule{},
+		},
+		{
+			name:     "exclude missing",
+			elements: []string{"r:" + etc + "/ssl/certs", "!" + etc + "/missing"},
+			exp:      []rule{{path: etc + "/ssl/certs", access: r, dir: true}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rules, err := convert(tc.elements)
+			must.NoError(t, err)
+			must.Eq(t, tc.exp, rules)
+		})
+	}
+}
+
+func Test_defaultPaths(t *testing.T) {
+	paths := defaultPaths()
+	must.SliceContains(t, paths, "?rw:/dev/null")
+	for _, path := range paths {
+		must.StrHasPrefix(t, Optional, path)
+	}
+
+	rules, err := convert(paths)
+	must.NoError(t, err)
+	must.Len(t, len(paths), rules)
+}
+
+func TestExpanded(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "c"} {
+		must.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o644))
+	}
+
+	n, err := Expanded(false, []string{"r:" + dir, "!" + dir + "/b"})
+	must.NoError(t, err)
+	must.Eq(t, 2, n)
+
+	_, err = Expanded(false, []string{"r:" + dir + "/missing"})
+	must.ErrorContains(t, err, "failed to stat unveil path")
+}
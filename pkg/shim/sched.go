@@ -0,0 +1,87 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package shim
+
+import (
+	"fmt"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// SchedPolicies are the scheduling policies a task may run with.
+var SchedPolicies = map[string]uint32{
+	"other": unix.SCHED_NORMAL,
+	"batch": unix.SCHED_BATCH,
+	"idle":  unix.SCHED_IDLE,
+}
+
+// IOClasses are the IO scheduling classes a task may run with.
+var IOClasses = map[string]int{
+	"best-effort": ioprioClassBE,
+	"idle":        ioprioClassIdle,
+}
+
+// from linux/ioprio.h
+const (
+	ioprioClassBE    = 2
+	ioprioClassIdle  = 3
+	ioprioClassShift = 13
+	ioprioWhoProcess = 1
+)
+
+// Sched is the CPU and IO scheduling of the task.
+type Sched struct {
+	Nice       int    `json:"nice,omitempty"`
+	Policy     string `json:"policy,omitempty"`
+	IOClass    string `json:"io_class,omitempty"`
+	IOPriority int    `json:"io_priority,omitempty"`
+}
+
+// schedule sets the scheduling attributes of the task, which are inherited by
+// the task process. On Linux these are attributes of each thread, so the shim
+// keeps to the thread that sets them, which is also the one that forks the task
+// process later on.
+func (s *Setup) schedule() error {
+	if s.Sched == nil {
+		return nil
+	}
+
+	runtime.LockOSThread()
+
+	if s.Sched.Nice != 0 || s.Sched.Policy != "" {
+		attr, err := unix.SchedGetAttr(0, 0)
+		if err != nil {
+			return fmt.Errorf("failed to get scheduling attributes: %w", err)
+		}
+		if s.Sched.Policy != "" {
+			policy, ok := SchedPolicies[s.Sched.Policy]
+			if !ok {
+				return fmt.Errorf("unknown scheduling policy %q", s.Sched.Policy)
+			}
+			attr.Policy = policy
+		}
+		// without a nice value the thread keeps the nice it inherited, which
+		// sched_getattr reports
+		if s.Sched.Nice != 0 {
+			attr.Nice = int32(s.Sched.Nice)
+		}
+		if err = unix.SchedSetAttr(0, attr, 0); err != nil {
+			return fmt.Errorf("failed to set scheduling attributes: %w", err)
+		}
+	}
+
+	if s.Sched.IOClass != "" {
+		class, ok := IOClasses[s.Sched.IOClass]
+		if !ok {
+			return fmt.Errorf("unknown io scheduling class %q", s.Sched.IOClass)
+		}
+		prio := class<<ioprioClassShift | s.Sched.IOPriority
+		if _, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, 0, uintptr(prio)); errno != 0 {
+			return fmt.Errorf("failed to set io priority: %w", errno)
+		}
+	}
+
+	return nil
+}
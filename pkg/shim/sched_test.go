@@ -0,0 +1,57 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package shim
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/shoenig/test/must"
+	"golang.org/x/sys/unix"
+)
+
+func TestSetup_schedule(t *testing.T) {
+	must.NoError(t, (&Setup{}).schedule())
+
+	// the thread setting the scheduling is locked and so discarded when the
+	// goroutine exits
+	done := make(chan *unix.SchedAttr)
+	go func() {
+		setup := &Setup{Sched: &Sched{Nice: 5, Policy: "batch", IOClass: "idle"}}
+		must.NoError(t, setup.schedule())
+		attr, err := unix.SchedGetAttr(0, 0)
+		must.NoError(t, err)
+		done <- attr
+	}()
+
+	attr := <-done
+	must.Eq(t, unix.SCHED_BATCH, attr.Policy)
+	must.Eq(t, 5, attr.Nice)
+}
+
+func TestSetup_schedule_inherited(t *testing.T) {
+	done := make(chan *unix.SchedAttr)
+	go func() {
+		runtime.LockOSThread()
+		must.NoError(t, unix.Setpriority(unix.PRIO_PROCESS, 0, 3))
+		setup := &Setup{Sched: &Sched{Policy: "batch"}}
+		must.NoError(t, setup.schedule())
+		attr, err := unix.SchedGetAttr(0, 0)
+		must.NoError(t, err)
+		done <- attr
+	}()
+
+	attr := <-done
+	must.Eq(t, unix.SCHED_BATCH, attr.Policy)
+	must.Eq(t, 3, attr.Nice)
+}
+
+func TestSetup_schedule_unknown(t *testing.T) {
+	done := make(chan error)
+	go func() {
+		setup := &Setup{Sched: &Sched{Policy: "fifo"}}
+		done <- setup.schedule()
+	}()
+	must.EqError(t, <-done, `unknown scheduling policy "fifo"`)
+}
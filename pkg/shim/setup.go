@@ -0,0 +1,292 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package shim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Setup is the privileged configuration of the sandbox. It is applied by the
+// shim inside the task namespaces, before dropping privileges to the task user.
+type Setup struct {
+	UID      int     `json:"uid"`
+	GID      int     `json:"gid"`
+	Mounts   []Mount `json:"mounts,omitempty"`
+	Hostname string  `json:"hostname,omitempty"`
+
+	// UserNamespace indicates the shim is already running as root inside a
+	// user namespace created by the task user.
+	UserNamespace bool `json:"userns,omitempty"`
+
+	// Rlimits are the resource limits of the task.
+	Rlimits []Rlimit `json:"rlimits,omitempty"`
+
+	// Sched is the CPU and IO scheduling of the task.
+	Sched *Sched `json:"sched,omitempty"`
+
+	// Proc is the mode of the /proc filesystem of the task.
+	Proc string `json:"proc,omitempty"`
+
+	// BestEffort indicates landlock rights the kernel cannot restrict are
+	// left unrestricted, rather than failing the task.
+	BestEffort bool `json:"best_effort,omitempty"`
+}
+
+// Mount is a mount(2) call made in the private mount namespace of the task.
+type Mount struct {
+	Source string  `json:"source,omitempty"`
+	Target string  `json:"target"`
+	FSType string  `json:"fstype,omitempty"`
+	Flags  uintptr `json:"flags,omitempty"`
+	Data   string  `json:"data,omitempty"`
+
+	// Optional mounts are skipped if the target does not exist.
+	Optional bool `json:"optional,omitempty"`
+}
+
+// BindMount returns the mounts needed to bind mount source onto target,
+// optionally made read-only.
+func BindMount(source, target string, readonly bool) []Mount {
+	mounts := []Mount{{
+		Source: source,
+		Target: target,
+		Flags:  unix.MS_BIND,
+	}}
+	if readonly {
+		// a bind mount can only be made read-only by remounting it
+		mounts = append(mounts, Mount{
+			Target: target,
+			Flags:  unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY,
+		})
+	}
+	return mounts
+}
+
+// cgroupMount returns the mount of the cgroup2 filesystem over the one of the
+// host. Inside the cgroup namespace of the task, only the cgroup subtree of
+// the task is visible.
+func cgroupMount() Mount {
+	return Mount{
+		Source: "cgroup2",
+		Target: "/sys/fs/cgroup",
+		FSType: "cgroup2",
+		Flags:  unix.MS_RDONLY | unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC,
+	}
+}
+
+// tmpMounts returns the mounts of a private tmpfs of the given size in bytes
+// at /tmp, also bind mounted over tmpdir if set. Pages of the tmpfs are charged
+// to the memory cgroup of the task writing them.
+func tmpMounts(size uint64, tmpdir string) []Mount {
+	mounts := []Mount{{
+		Source: "tmpfs",
+		Target: "/tmp",
+		FSType: "tmpfs",
+		Flags:  unix.MS_NOSUID | unix.MS_NODEV,
+		Data:   fmt.Sprintf("mode=1777,size=%d", size),
+	}}
+	if tmpdir != "" {
+		mounts = append(mounts, BindMount("/tmp", tmpdir, false)...)
+	}
+	return mounts
+}
+
+// The modes of the /proc filesystem of a task.
+const (
+	// ProcDefault is the procfs mounted by unshare --mount-proc.
+	ProcDefault = ""
+
+	// ProcHardened hides processes of other users, masks sensitive kernel
+	// interfaces, and makes /proc/sys read-only.
+	ProcHardened = "hardened"
+
+	// ProcPID is ProcHardened, but with only the process directories.
+	ProcPID = "pid"
+)
+
+// procMasked are the kernel interfaces hidden behind /dev/null in a hardened
+// /proc, as they may leak information about the host
+var procMasked = []string{
+	"/proc/kcore",
+	"/proc/keys",
+	"/proc/sysrq-trigger",
+	"/proc/timer_list",
+}
+
+// procMounts returns the mounts of a new procfs over the one mounted by unshare,
+// hardened according to mode.
+func procMounts(mode string) []Mount {
+	if mode == ProcDefault {
+		return nil
+	}
+
+	data := "hidepid=invisible"
+	if mode == ProcPID {
+		data += ",subset=pid"
+	}
+
+	mounts := []Mount{{
+		Source: "proc",
+		Target: "/proc",
+		FSType: "proc",
+		Flags:  unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC,
+		Data:   data,
+	}}
+
+	// with subset=pid none of the following exist
+	for _, path := range procMasked {
+		mounts = append(mounts, optional(BindMount("/dev/null", path, true))...)
+	}
+	mounts = append(mounts, optional(BindMount("/proc/sys", "/proc/sys", true))...)
+	return mounts
+}
+
+// optional marks each of mounts as optional.
+func optional(mounts []Mount) []Mount {
+	for i := range mounts {
+		mounts[i].Optional = true
+	}
+	return mounts
+}
+
+func (s *Setup) encode() string {
+	b, err := json.Marshal(s)
+	if err != nil {
+		// not possible; all fields are plain values
+		panic(fmt.Sprintf("plugin: unable to encode setup: %v", err))
+	}
+	return string(b)
+}
+
+func decodeSetup(arg string) (*Setup, error) {
+	var s Setup
+	if err := json.Unmarshal([]byte(arg), &s); err != nil {
+		return nil, fmt.Errorf("failed to decode sandbox setup: %w", err)
+	}
+	return &s, nil
+}
+
+// prepare the sandbox from inside the task namespaces, while the shim is
+// still privileged.
+func (s *Setup) prepare() error {
+	if err := s.mount(); err != nil {
+		return err
+	}
+	if err := s.uts(); err != nil {
+		return err
+	}
+	if err := s.limit(); err != nil {
+		return err
+	}
+	if err := s.schedule(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// mount performs each mount of the setup, in order.
+func (s *Setup) mount() error {
+	for _, m := range s.Mounts {
+		if m.Optional && !exists(m.Target) {
+			continue
+		}
+		err := unix.Mount(m.Source, m.Target, m.FSType, m.Flags, m.Data)
+		if errors.Is(err, unix.EPERM) && m.Flags&unix.MS_REMOUNT != 0 {
+			// in a user namespace the flags of mounts from the parent
+			// namespace are locked, and a remount must keep them
+			err = m.remount()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to mount %q: %w", m.Target, err)
+		}
+	}
+	return nil
+}
+
+// remount performs the remount m again, keeping the flags of the mount at
+// the target.
+func (m Mount) remount() error {
+	var st unix.Statfs_t
+	if err := unix.Statfs(m.Target, &st); err != nil {
+		return err
+	}
+	flags := m.Flags | lockedFlags(uint64(st.Flags))
+	return unix.Mount(m.Source, m.Target, m.FSType, flags, m.Data)
+}
+
+// lockedFlags converts the statfs(2) flags of a mount into the mount(2) flags
+// which cannot be cleared by a remount in a user namespace.
+func lockedFlags(statfs uint64) uintptr {
+	var flags uintptr
+	for st, ms := range map[uint64]uintptr{
+		unix.ST_RDONLY:     unix.MS_RDONLY,
+		unix.ST_NOSUID:     unix.MS_NOSUID,
+		unix.ST_NODEV:      unix.MS_NODEV,
+		unix.ST_NOEXEC:     unix.MS_NOEXEC,
+		unix.ST_NOATIME:    unix.MS_NOATIME,
+		unix.ST_NODIRATIME: unix.MS_NODIRATIME,
+		unix.ST_RELATIME:   unix.MS_RELATIME,
+	} {
+		if statfs&st != 0 {
+			flags |= ms
+		}
+	}
+	return flags
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// uts sets the hostname of the task, which must be in its own uts namespace.
+func (s *Setup) uts() error {
+	if s.Hostname == "" {
+		return nil
+	}
+	if err := unix.Sethostname([]byte(s.Hostname)); err != nil {
+		return fmt.Errorf("failed to set hostname: %w", err)
+	}
+	return nil
+}
+
+// limit sets the resource limits of the task, which are inherited through
+// execve(2). Raising a hard limit requires the shim to still be privileged.
+func (s *Setup) limit() error {
+	for _, r := range s.Rlimits {
+		// the syscall package makes os/exec pass the new limit of open
+		// files on to the task, rather than the one the shim started with
+		limit := &syscall.Rlimit{Cur: r.Soft, Max: r.Hard}
+		if err := syscall.Setrlimit(r.Resource, limit); err != nil {
+			return fmt.Errorf("failed to set rlimit %s: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// drop the privileges of the shim to the task user; the change applies to
+// every thread of the process.
+func (s *Setup) drop() error {
+	if s.UserNamespace {
+		// already unprivileged on the host; and setgroups is denied in a user
+		// namespace created by an unprivileged user
+		return nil
+	}
+	if err := syscall.Setgroups(nil); err != nil {
+		return fmt.Errorf("failed to clear supplementary groups: %w", err)
+	}
+	if err := syscall.Setgid(s.GID); err != nil {
+		return fmt.Errorf("failed to set gid: %w", err)
+	}
+	if err := syscall.Setuid(s.UID); err != nil {
+		return fmt.Errorf("failed to set uid: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package shim
+
+import (
+	"testing"
+
+	"github.com/shoenig/test/must"
+	"golang.org/x/sys/unix"
+)
+
+func TestBindMount(t *testing.T) {
+	t.Run("writable", func(t *testing.T) {
+		mounts := BindMount("/a", "/b", false)
+		must.Eq(t, []Mount{
+			{Source: "/a", Target: "/b", Flags: unix.MS_BIND},
+		}, mounts)
+	})
+
+	t.Run("readonly", func(t *testing.T) {
+		mounts := BindMount("/a", "/b", true)
+		must.Eq(t, []Mount{
+			{Source: "/a", Target: "/b", Flags: unix.MS_BIND},
+			{Target: "/b", Flags: unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY},
+		}, mounts)
+	})
+}
+
+func Test_lockedFlags(t *testing.T) {
+	must.Eq(t, 0, lockedFlags(0))
+	must.Eq(t, unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, lockedFlags(unix.ST_NOSUID|unix.ST_NODEV|unix.ST_NOEXEC))
+	must.Eq(t, unix.MS_RDONLY|unix.MS_RELATIME, lockedFlags(unix.ST_RDONLY|unix.ST_RELATIME))
+}
+
+func Test_tmpMounts(t *testing.T) {
+	t.Run("tmp only", func(t *testing.T) {
+		mounts := tmpMounts(64<<20, "")
+		must.Eq(t, []Mount{{
+			Source: "tmpfs",
+			Target: "/tmp",
+			FSType: "tmpfs",
+			Flags:  unix.MS_NOSUID | unix.MS_NODEV,
+			Data:   "mode=1777,size=67108864",
+		}}, mounts)
+	})
+
+	t.Run("with tmpdir", func(t *testing.T) {
+		mounts := tmpMounts(64<<20, "/alloc/task/tmp")
+		must.SliceLen(t, 2, mounts)
+		must.Eq(t, Mount{
+			Source: "/tmp",
+			Target: "/alloc/task/tmp",
+			Flags:  unix.MS_BIND,
+		}, mounts[1])
+	})
+}
+
+func Test_procMounts(t *testing.T) {
+	must.SliceEmpty(t, procMounts(ProcDefault))
+
+	hardened := procMounts(ProcHardened)
+	must.Eq(t, "hidepid=invisible", hardened[0].Data)
+	must.False(t, hardened[0].Optional)
+	for _, m := range hardened[1:] {
+		must.True(t, m.Optional)
+	}
+	must.Eq(t, Mount{
+		Source:   "/proc/sys",
+		Target:   "/proc/sys",
+		Flags:    unix.MS_BIND,
+		Optional: true,
+	}, hardened[len(hardened)-2])
+
+	pid := procMounts(ProcPID)
+	must.Eq(t, "hidepid=invisible,subset=pid", pid[0].Data)
+}
+
+func TestSetup_encode(t *testing.T) {
+	setup := &Setup{
+		UID:    80000,
+		GID:    80000,
+		Mounts: BindMount("/alloc/task/resolv.conf", "/etc/resolv.conf", true),
+	}
+
+	result, err := decodeSetup(setup.encode())
+	must.NoError(t, err)
+	must.Eq(t, setup, result)
+
+	_, err = decodeSetup("not json")
+	must.ErrorContains(t, err, "failed to decode sandbox setup")
+}
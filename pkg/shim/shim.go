@@ -31,11 +31,28 @@ type Options struct {
 	UnveilPaths    []string
 	UnveilDefaults bool
 	OOMScoreAdj    int
+	Hostname       string
+	UserNamespace  bool
+	TmpfsSize      uint64
+	TmpfsTmpDir    bool
+	Proc           string
+	Rlimits        []Rlimit
+	Sched          *Sched
+	CPUIdle        bool
+	CPUWeight      uint64
+	CPUPeriod      uint64
+	CPUBurst       uint64
+	MemoryHigh     uint64
+	MemoryMin      uint64
+	SwapMax        string
+	CpusetMems     string
+	BestEffort     bool
 }
 
 // Environment represents runtime configuration.
 type Environment struct {
 	User         string            // user the command will run as (may be empty / synthetic)
+	Home         string            // home directory of the user, if not the one of its passwd entry
 	OutPipe      string            // io pipe path for stdout
 	ErrPipe      string            // io pipe path for stderr
 	Env          map[string]string // environment variables
@@ -44,8 +61,9 @@ type Environment struct {
 	Net          string            // allocation network namespace path
 	Memory       uint64            // memory in megabytes
 	MemoryMax    uint64            // memory_max in megabytes
-	CPUBandwidth uint64            // cpu / cores bandwidth
+	CPUBandwidth uint64            // cpu / cores bandwidth per cpu period
 	OOMScoreAdj  int               // oom_score_adj for the task
+	Mounts       []Mount           // mounts made inside the task mount namespace
 }
 
 type ExecTwo interface {
@@ -84,6 +102,7 @@ func New(env *Environment, opts *Options) ExecTwo {
 		env:  env,
 		opts: opts,
 		cpu:  new(resources.TrackCPU),
+		io:   new(resources.TrackIO),
 	}
 }
 
@@ -96,6 +115,7 @@ func Recover(pid int, env *Environment) ExecTwo {
 		waiter:  process.WaitPID(pid, env.TaskDir).Wait(),
 		signals: process.Signals(pid),
 		cpu:     new(resources.TrackCPU),
+		io:      new(resources.TrackIO),
 	}
 }
 
@@ -107,6 +127,7 @@ type exe struct {
 	// comes from runtime
 	pid     int
 	cpu     *resources.TrackCPU
+	io      *resources.TrackIO
 	waiter  process.WaitCh
 	signals process.Signaler
 }
@@ -117,6 +138,11 @@ func (e *exe) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to lookup user: %w", err)
 	}
 
+	// a dynamic workload user has a home directory made for the task
+	if e.env.Home != "" {
+		home = e.env.Home
+	}
+
 	// find out cgroup file descriptor
 	fd, cleanup, err := e.openCG()
 	if err != nil {
@@ -214,11 +240,19 @@ func (e *exe) Stats() *resources.Utilization {
 
 	cpuStatsS, _ := e.readCG("cpu.stat")
 	usr, system, total := extractCPU(cpuStatsS)
+	throttlePeriods, throttleTime := extractThrottling(cpuStatsS)
 	userPct, systemPct, totalPct := e.cpu.Percent(usr, system, total)
 
 	specs := resources.GetSpecs()
 	ticks := (.01 * totalPct) * resources.Percent(int(specs.Ticks())/specs.Cores)
 
+	ioStatS, _ := e.readCG("io.stat")
+	blockIO := extractIO(ioStatS, blockDevice)
+	e.io.Rates(blockIO)
+
+	memEventsS, _ := e.readCG("memory.events")
+	memEvents := extractMemoryEvents(memEventsS)
+
 	return &resources.Utilization{
 		// memory stats
 		Memory: uint64(memCurrent),
@@ -230,6 +264,16 @@ func (e *exe) Stats() *resources.Utilization {
 		User:    userPct,
 		Percent: totalPct,
 		Ticks:   ticks,
+
+		// cpu bandwidth throttling
+		ThrottlePeriods: throttlePeriods,
+		ThrottleTime:    throttleTime,
+
+		// io stats
+		IO: blockIO,
+
+		// memory events
+		MemoryEvents: memEvents,
 	}
 }
 
@@ -275,9 +319,7 @@ func flatten(user, home string, env map[string]string) []string {
 	env["HOME"] = home
 
 	// set the tmp directory to the one made for the task
-	parent := filepath.Dir(env["NOMAD_TASK_DIR"])
-	tmp := filepath.Join(parent, "tmp")
-	env["TMPDIR"] = tmp
+	env["TMPDIR"] = tmpdir(env)
 
 	// copy environment variables into list form
 	for k, v := range env {
@@ -294,6 +336,13 @@ func flatten(user, home string, env map[string]string) []string {
 	return result
 }
 
+// tmpdir returns the tmp directory made for the task, next to the task
+// directory
+func tmpdir(env map[string]string) string {
+	parent := filepath.Dir(env["NOMAD_TASK_DIR"])
+	return filepath.Join(parent, "tmp")
+}
+
 func self() string {
 	executable, err := os.Executable()
 	if err != nil {
@@ -318,35 +367,84 @@ func self() string {
 func (e *exe) parameters(uid, gid int) []string {
 	var result []string
 
-	// setup nsenter if task was assigned a network namespace
-	if net := e.env.Net; net != "" {
-		result = append(
-			result,
-			"nsenter",
-			"--no-fork",
-			fmt.Sprintf("--net=%s", net),
-			"--",
-		)
+	// setup nsenter if task was assigned a network namespace, or if the task
+	// user must create its own user namespace
+	if net := e.env.Net; net != "" || e.opts.UserNamespace {
+		result = append(result, "nsenter", "--no-fork")
+		if net != "" {
+			result = append(result, fmt.Sprintf("--net=%s", net))
+		}
+		if e.opts.UserNamespace {
+			// create the user namespace as the task user, who is then mapped
+			// to root inside of it, without privileges on the host
+			result = append(result,
+				fmt.Sprintf("--setuid=%d", uid),
+				fmt.Sprintf("--setgid=%d", gid),
+			)
+		}
+		result = append(result, "--")
 	}
 
-	// setup unshare for ipc, pid namespaces
+	// setup unshare for ipc, pid, mount, cgroup namespaces; the shim drops
+	// privileges to the task user after setting up the mount namespace
 	result = append(result,
 		"unshare",
 		"--ipc",
 		"--pid",
 		"--mount-proc",
+		"--cgroup",
+	)
+
+	// setup a user namespace in which the task user is root
+	if e.opts.UserNamespace {
+		result = append(result, "--user", "--map-root-user")
+		uid, gid = 0, 0
+	}
+
+	// setup a uts namespace if the task has its own hostname
+	if e.opts.Hostname != "" {
+		result = append(result, "--uts")
+	}
+
+	result = append(result,
 		"--fork",
 		"--kill-child=SIGKILL",
-		fmt.Sprintf("--setuid=%d", uid),
-		fmt.Sprintf("--setgid=%d", gid),
 		"--",
 	)
 
+	// remount /sys/fs/cgroup so the task sees only its own cgroup
+	mounts := append([]Mount{cgroupMount()}, e.env.Mounts...)
+
+	// mount a private /tmp (and maybe TMPDIR) if requested
+	if size := e.opts.TmpfsSize; size > 0 {
+		var dir string
+		if e.opts.TmpfsTmpDir {
+			dir = tmpdir(e.env.Env)
+		}
+		mounts = append(mounts, tmpMounts(size, dir)...)
+	}
+
+	// replace /proc with a hardened one if requested
+	mounts = append(mounts, procMounts(e.opts.Proc)...)
+
+	setup := &Setup{
+		UID:           uid,
+		GID:           gid,
+		Mounts:        mounts,
+		Hostname:      e.opts.Hostname,
+		UserNamespace: e.opts.UserNamespace,
+		Proc:          e.opts.Proc,
+		Rlimits:       e.opts.Rlimits,
+		Sched:         e.opts.Sched,
+		BestEffort:    e.opts.BestEffort,
+	}
+
 	// setup ourself '$0 exec2-shim' for unveil
 	result = append(result, self(), SubCommand)
 	result = append(result, strconv.FormatBool(e.opts.UnveilDefaults))
 	result = append(result, e.env.OutPipe)
 	result = append(result, e.env.ErrPipe)
+	result = append(result, setup.encode())
 	result = append(result, e.opts.UnveilPaths...)
 	result = append(result, "--")
 
@@ -379,9 +477,30 @@ func (e *exe) prepare(ctx context.Context, home string, fd, uid, gid int) *exec.
 // set resource constraints via cgroups
 func (e *exe) constrain() error {
 	// set cpu bandwidth
-	if err := e.writeCG("cpu.max", fmt.Sprintf("%d 100000", e.env.CPUBandwidth)); err != nil {
+	period := e.opts.CPUPeriod
+	if period == 0 {
+		period = resources.DefaultPeriod
+	}
+	if err := e.writeCG("cpu.max", fmt.Sprintf("%d %d", e.env.CPUBandwidth, period)); err != nil {
 		return err
 	}
+	if e.opts.CPUBurst > 0 {
+		if err := e.writeCG("cpu.max.burst", strconv.FormatUint(e.opts.CPUBurst, 10)); err != nil {
+			return err
+		}
+	}
+
+	// set cpu scheduling of the task relative to other tasks
+	if e.opts.CPUWeight > 0 {
+		if err := e.writeCG("cpu.weight", strconv.FormatUint(e.opts.CPUWeight, 10)); err != nil {
+			return err
+		}
+	}
+	if e.opts.CPUIdle {
+		if err := e.writeCG("cpu.idle", "1"); err != nil {
+			return err
+		}
+	}
 
 	// set memory limits
 	switch e.env.MemoryMax {
@@ -397,6 +516,32 @@ func (e *exe) constrain() error {
 			return err
 		}
 	}
+
+	// set soft memory limits
+	if e.opts.MemoryHigh > 0 {
+		if err := e.writeCG("memory.high", fmt.Sprintf("%d", e.opts.MemoryHigh)); err != nil {
+			return err
+		}
+	}
+	if e.opts.MemoryMin > 0 {
+		if err := e.writeCG("memory.min", fmt.Sprintf("%d", e.opts.MemoryMin)); err != nil {
+			return err
+		}
+	}
+
+	// set swap limit
+	if e.opts.SwapMax != "" {
+		if err := e.writeCG("memory.swap.max", e.opts.SwapMax); err != nil {
+			return err
+		}
+	}
+
+	// keep memory allocations on the numa nodes of the task cpus
+	if e.opts.CpusetMems != "" {
+		if err := e.writeCG("cpuset.mems", e.opts.CpusetMems); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -445,6 +590,100 @@ func extractCPU(s string) (user, system, total resources.MicroSecond) {
 	return
 }
 
+// extractThrottling parses the number of throttled periods and the time spent
+// throttled from the content of cpu.stat, with the time in nanoseconds.
+func extractThrottling(s string) (periods, nanos uint64) {
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+		v, _ := strconv.ParseUint(value, 10, 64)
+		switch key {
+		case "nr_throttled":
+			periods = v
+		case "throttled_usec":
+			nanos = v * 1000
+		}
+	}
+	return
+}
+
+// extractMemoryEvents parses the content of memory.events, which has one line
+// per event in the form "high 12".
+func extractMemoryEvents(s string) resources.MemoryEvents {
+	var events resources.MemoryEvents
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+		v, _ := strconv.ParseUint(value, 10, 64)
+		switch key {
+		case "low":
+			events.Low = v
+		case "high":
+			events.High = v
+		case "max":
+			events.Max = v
+		case "oom":
+			events.OOM = v
+		case "oom_kill":
+			events.OOMKill = v
+		}
+	}
+	return events
+}
+
+// extractIO parses the content of io.stat, which has one line per device in
+// the form "MAJ:MIN rbytes=1 wbytes=2 rios=3 wios=4 dbytes=5 dios=6". The
+// name function is used to resolve the device number into a device name.
+func extractIO(s string, name func(string) string) []*resources.BlockIO {
+	var result []*resources.BlockIO
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		dev := &resources.BlockIO{Device: name(fields[0])}
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			v, _ := strconv.ParseUint(value, 10, 64)
+			switch key {
+			case "rbytes":
+				dev.ReadBytes = v
+			case "wbytes":
+				dev.WriteBytes = v
+			case "rios":
+				dev.ReadIOs = v
+			case "wios":
+				dev.WriteIOs = v
+			case "dbytes":
+				dev.DiscardBytes = v
+			}
+		}
+		result = append(result, dev)
+	}
+	return result
+}
+
+// blockDevice resolves a "MAJ:MIN" device number into the kernel name of the
+// block device (e.g. "sda", "nvme0n1p1"), falling back to the device number
+// if the device cannot be found in sysfs.
+func blockDevice(majmin string) string {
+	target, err := os.Readlink(filepath.Join("/sys/dev/block", majmin))
+	if err != nil {
+		return majmin
+	}
+	return filepath.Base(target)
+}
+
 // blockPIDs blocks until there are no more live processes in the cgroup, and returns true
 // if the timeout is exceeded or an error occurs.
 func (e *exe) blockPIDs(timeout time.Duration) bool {
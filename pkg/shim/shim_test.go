@@ -0,0 +1,57 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package shim
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad-driver-exec2/pkg/resources"
+	"github.com/shoenig/test/must"
+)
+
+func Test_extractIO(t *testing.T) {
+	names := map[string]string{
+		"8:0":   "sda",
+		"259:0": "nvme0n1",
+	}
+	name := func(majmin string) string {
+		if n, exists := names[majmin]; exists {
+			return n
+		}
+		return majmin
+	}
+
+	content := `8:0 rbytes=1024 wbytes=2048 rios=3 wios=4 dbytes=512 dios=1
+259:0 rbytes=10 wbytes=20 rios=1 wios=2 dbytes=0 dios=0
+253:1 rbytes=5 wbytes=0 rios=1 wios=0 dbytes=0 dios=0`
+
+	result := extractIO(content, name)
+	must.Eq(t, []*resources.BlockIO{
+		{Device: "sda", ReadBytes: 1024, WriteBytes: 2048, ReadIOs: 3, WriteIOs: 4, DiscardBytes: 512},
+		{Device: "nvme0n1", ReadBytes: 10, WriteBytes: 20, ReadIOs: 1, WriteIOs: 2},
+		{Device: "253:1", ReadBytes: 5, ReadIOs: 1},
+	}, result)
+}
+
+func Test_extractIO_empty(t *testing.T) {
+	result := extractIO("", blockDevice)
+	must.SliceEmpty(t, result)
+}
+
+func Test_extractThrottling(t *testing.T) {
+	s := "usage_usec 900\nuser_usec 600\nsystem_usec 300\nnr_periods 40\nnr_throttled 7\nthrottled_usec 2500\nnr_bursts 2\nburst_usec 100"
+	periods, nanos := extractThrottling(s)
+	must.Eq(t, 7, periods)
+	must.Eq(t, 2_500_000, nanos)
+}
+
+func Test_extractMemoryEvents(t *testing.T) {
+	s := "low 0\nhigh 12\nmax 3\noom 1\noom_kill 1\noom_group_kill 0"
+	must.Eq(t, resources.MemoryEvents{
+		High:    12,
+		Max:     3,
+		OOM:     1,
+		OOMKill: 1,
+	}, extractMemoryEvents(s))
+}
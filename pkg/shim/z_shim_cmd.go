@@ -29,6 +29,10 @@ const (
 	// ExitBadLogging indicates the shim has terminated early due to being unable
 	// to open stdout or stderr output files (fifos).
 	ExitBadLogging = 41
+
+	// ExitBadCredentials indicates the shim has terminated early due to being
+	// unable to drop privileges to the task user.
+	ExitBadCredentials = 42
 )
 
 // init is the entrypoint for the 'nomad e2e-shim' invocation of nomad
@@ -40,8 +44,9 @@ const (
 // 2. true/false       <- include default unveil paths
 // 3. <stdout path>    <- path to named pipe for standard output
 // 4. <stderr path>    <- path to named pipe for standard error
-// 5. [mode:path, ...] <- list of additional unveil paths
-// 6. --               <- sentinel between following commands
+// 5. <setup>          <- json encoded privileged sandbox setup
+// 6. [mode:path, ...] <- list of additional unveil paths
+// 7. --               <- sentinel between following commands
 func init() {
 	subproc.Do(SubCommand, func() int {
 		// we need to ignore the stop signal (which is sent to the entire
@@ -53,21 +58,41 @@ func init() {
 			<-sigs // do nothing; say alive
 		}()
 
-		if n := len(os.Args); n <= 4 {
+		if n := len(os.Args); n <= 5 {
 			subproc.Print("failed to invoke e2e-shim with sufficient args: %d", n)
 			return ExitWrongArgs
 		}
 
 		// get the unveil paths and the rest of the command(s) to run
 		// from our command arguments
-		args := os.Args[5:] // chop off 'nomad exec2-shim <defaults>'
+		args := os.Args[6:] // chop off 'nomad exec2-shim <defaults> <out> <err> <setup>'
 		defaults := os.Args[2] == "true"
 		outPipePath := os.Args[3]
 		errPipePath := os.Args[4]
 		paths, commands := split(args)
+		if len(commands) == 0 {
+			subproc.Print("failed to invoke e2e-shim with a command")
+			return ExitWrongArgs
+		}
 		paths = append(paths, "w:"+outPipePath)
 		paths = append(paths, "w:"+errPipePath)
 
+		setup, err := decodeSetup(os.Args[5])
+		if err != nil {
+			subproc.Print("failed to invoke e2e-shim with valid setup: %v", err)
+			return ExitWrongArgs
+		}
+
+		// configure the task namespaces while we still have privileges, but
+		// only report a failure once the output pipes are open
+		setupErr := setup.prepare()
+
+		// drop to the task user before doing anything else
+		if err = setup.drop(); err != nil {
+			subproc.Print("failed to drop privileges: %v", err)
+			return ExitBadCredentials
+		}
+
 		stdout, stderr, err := util.OpenPipes(outPipePath, errPipePath)
 		if err != nil {
 			subproc.Print("failed to open output pipes: %v", err)
@@ -79,9 +104,14 @@ func init() {
 			_, _ = io.WriteString(stderr, fmt.Sprintf(format+"\n", args...))
 		}
 
+		if setupErr != nil {
+			debug("unable to setup sandbox: %v", setupErr)
+			return subproc.ExitFailure
+		}
+
 		// use landlock to isolate this process and child processes to the
 		// set of given filepaths
-		if err := lockdown(defaults, paths); err != nil {
+		if err := lockdown(defaults, paths, setup.BestEffort); err != nil {
 			debug("unable to lockdown: %v", err)
 			return subproc.ExitFailure
 		}
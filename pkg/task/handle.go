@@ -21,6 +21,17 @@ type latestStats struct {
 	utilization *resources.Utilization
 }
 
+// memoryHigh is the throttling of the task by memory.high, as last seen.
+type memoryHigh struct {
+	count uint64    // times usage exceeded memory.high
+	last  time.Time // when the count last increased
+	known bool      // whether count was seen since the task started
+}
+
+// throttleQuiet is how long the memory.high count must stay the same before
+// the task is no longer considered throttled.
+const throttleQuiet = 5 * time.Minute
+
 // A Handle is used by the driver plugin to keep track of active tasks.
 //
 // Handle must be comletetly thread-safe; all operations must go through
@@ -36,32 +47,37 @@ type Handle struct {
 	result    *drivers.ExitResult
 	clock     libtime.Clock
 	pid       int
+	landlock  string // enforcement of the landlock rights of the task
 	stats     latestStats
+	high      memoryHigh
 }
 
-func NewHandle(runner shim.ExecTwo, config *drivers.TaskConfig) (*Handle, time.Time) {
+func NewHandle(runner shim.ExecTwo, config *drivers.TaskConfig, landlock string) (*Handle, time.Time) {
 	clock := libtime.SystemClock()
 	now := clock.Now()
 	return &Handle{
-		pid:     runner.PID(),
-		runner:  runner,
-		config:  config,
-		state:   drivers.TaskStateRunning,
-		clock:   clock,
-		started: now,
-		result:  nil,
+		pid:      runner.PID(),
+		runner:   runner,
+		config:   config,
+		state:    drivers.TaskStateRunning,
+		clock:    clock,
+		started:  now,
+		result:   nil,
+		landlock: landlock,
+		high:     memoryHigh{known: true},
 	}, now
 }
 
-func RecreateHandle(runner shim.ExecTwo, config *drivers.TaskConfig, started time.Time) *Handle {
+func RecreateHandle(runner shim.ExecTwo, config *drivers.TaskConfig, started time.Time, landlock string) *Handle {
 	return &Handle{
-		pid:     runner.PID(),
-		runner:  runner,
-		config:  config,
-		state:   drivers.TaskStateUnknown,
-		clock:   libtime.SystemClock(),
-		started: started,
-		result:  nil,
+		pid:      runner.PID(),
+		runner:   runner,
+		config:   config,
+		state:    drivers.TaskStateUnknown,
+		clock:    libtime.SystemClock(),
+		started:  started,
+		result:   nil,
+		landlock: landlock,
 	}
 }
 
@@ -80,6 +96,37 @@ func (h *Handle) Stats() *resources.Utilization {
 	return h.stats.utilization
 }
 
+// Config returns the config of the task.
+func (h *Handle) Config() *drivers.TaskConfig {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	return h.config
+}
+
+// Throttled records the number of times the task exceeded memory.high, and
+// reports whether the task started being throttled. Throttling which goes on
+// is reported once, until the count stays the same for a while. The count of
+// a recovered task is taken as is, as its throttling may have been reported
+// before the client restarted.
+func (h *Handle) Throttled(count uint64) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := h.clock.Now()
+	if !h.high.known {
+		h.high = memoryHigh{count: count, known: true}
+		return false
+	}
+	if count <= h.high.count {
+		return false
+	}
+
+	started := now.Sub(h.high.last) > throttleQuiet
+	h.high.count = count
+	h.high.last = now
+	return started
+}
+
 func (h *Handle) IsRunning() bool {
 	h.lock.RLock()
 	defer h.lock.RUnlock()
@@ -90,16 +137,35 @@ func (h *Handle) Status() *drivers.TaskStatus {
 	h.lock.RLock()
 	defer h.lock.RUnlock()
 
+	attributes := map[string]string{
+		"pid": strconv.Itoa(h.pid),
+	}
+	if h.landlock != "" {
+		attributes["landlock"] = h.landlock
+	}
+
 	return &drivers.TaskStatus{
-		ID:          h.config.ID,
-		Name:        h.config.Name,
-		State:       h.state,
-		StartedAt:   h.started,
-		CompletedAt: h.completed,
-		ExitResult:  h.result,
-		DriverAttributes: map[string]string{
-			"pid": strconv.Itoa(h.pid),
-		},
+		ID:               h.config.ID,
+		Name:             h.config.Name,
+		State:            h.state,
+		StartedAt:        h.started,
+		CompletedAt:      h.completed,
+		ExitResult:       h.result,
+		DriverAttributes: attributes,
+	}
+}
+
+// Event returns an event of the task with the given message.
+func (h *Handle) Event(message string) *drivers.TaskEvent {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	return &drivers.TaskEvent{
+		TaskID:    h.config.ID,
+		TaskName:  h.config.Name,
+		AllocID:   h.config.AllocID,
+		Timestamp: h.clock.Now(),
+		Message:   message,
 	}
 }
 
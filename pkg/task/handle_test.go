@@ -0,0 +1,59 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build linux
+
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shoenig/test/must"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Since(t time.Time) time.Duration {
+	return c.now.Sub(t)
+}
+
+func (c *testClock) SinceMS(t time.Time) int {
+	return int(c.Since(t).Milliseconds())
+}
+
+func TestHandle_Throttled(t *testing.T) {
+	clock := &testClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	t.Run("started", func(t *testing.T) {
+		h := &Handle{clock: clock, high: memoryHigh{known: true}}
+
+		must.False(t, h.Throttled(0))
+		must.True(t, h.Throttled(3))
+
+		// ongoing throttling is reported once
+		clock.now = clock.now.Add(time.Minute)
+		must.False(t, h.Throttled(3))
+		must.False(t, h.Throttled(10))
+
+		// throttling again after a quiet period is reported again
+		clock.now = clock.now.Add(throttleQuiet + time.Second)
+		must.False(t, h.Throttled(10))
+		must.True(t, h.Throttled(11))
+	})
+
+	t.Run("recovered", func(t *testing.T) {
+		h := &Handle{clock: clock}
+
+		// the count of a recovered task may have been reported already
+		must.False(t, h.Throttled(20))
+		must.False(t, h.Throttled(20))
+		must.True(t, h.Throttled(21))
+	})
+}
@@ -15,5 +15,6 @@ type State struct {
 	TaskConfig *drivers.TaskConfig
 	StartedAt  time.Time
 	PID        int
+	Landlock   string
 	Cancel     func()
 }
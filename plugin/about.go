@@ -52,14 +52,103 @@ var driverConfigSpec = hclspec.NewObject(map[string]*hclspec.Spec{
 		hclspec.NewLiteral("false"),
 	),
 	"unveil_paths": hclspec.NewAttr("unveil_paths", "list(string)", false),
+	"auto_unveil_command": hclspec.NewDefault(
+		hclspec.NewAttr("auto_unveil_command", "bool", false),
+		hclspec.NewLiteral("false"),
+	),
+	"unveil_allow": hclspec.NewAttr("unveil_allow", "map(string)", false),
+	"unveil_deny":  hclspec.NewAttr("unveil_deny", "list(string)", false),
+	"allow_user_namespace": hclspec.NewDefault(
+		hclspec.NewAttr("allow_user_namespace", "bool", false),
+		hclspec.NewLiteral("false"),
+	),
+	"landlock": hclspec.NewDefault(
+		hclspec.NewAttr("landlock", "string", false),
+		hclspec.NewLiteral(`"mandatory"`),
+	),
+	"initiate_network": hclspec.NewDefault(
+		hclspec.NewAttr("initiate_network", "bool", false),
+		hclspec.NewLiteral("false"),
+	),
+	"proc": hclspec.NewDefault(
+		hclspec.NewAttr("proc", "string", false),
+		hclspec.NewLiteral(`"default"`),
+	),
+	"rlimits":     hclspec.NewAttr("rlimits", "map(string)", false),
+	"rlimits_max": hclspec.NewAttr("rlimits_max", "map(string)", false),
+	"rlimits_by_task": hclspec.NewDefault(
+		hclspec.NewAttr("rlimits_by_task", "bool", false),
+		hclspec.NewLiteral("true"),
+	),
+	"users": hclspec.NewAttr("users", "list(string)", false),
+	"oom_score_adj_max": hclspec.NewDefault(
+		hclspec.NewAttr("oom_score_adj_max", "number", false),
+		hclspec.NewLiteral("1000"),
+	),
+	"nice_min": hclspec.NewDefault(
+		hclspec.NewAttr("nice_min", "number", false),
+		hclspec.NewLiteral("0"),
+	),
+	"io_priority_min": hclspec.NewDefault(
+		hclspec.NewAttr("io_priority_min", "number", false),
+		hclspec.NewLiteral("4"),
+	),
+	"cpu_weight_max": hclspec.NewDefault(
+		hclspec.NewAttr("cpu_weight_max", "number", false),
+		hclspec.NewLiteral("100"),
+	),
+	"swap": hclspec.NewDefault(
+		hclspec.NewAttr("swap", "string", false),
+		hclspec.NewLiteral(`"unlimited"`),
+	),
+	"profile": hclspec.NewBlockMap("profile", []string{"name"}, hclspec.NewObject(map[string]*hclspec.Spec{
+		"unveil": hclspec.NewAttr("unveil", "list(string)", false),
+	})),
+	"policy": hclspec.NewBlockMap("policy", []string{"name"}, hclspec.NewObject(map[string]*hclspec.Spec{
+		"namespace":            hclspec.NewAttr("namespace", "string", false),
+		"job":                  hclspec.NewAttr("job", "string", false),
+		"unveil_paths":         hclspec.NewAttr("unveil_paths", "list(string)", false),
+		"unveil_by_task":       hclspec.NewAttr("unveil_by_task", "bool", false),
+		"auto_unveil_command":  hclspec.NewAttr("auto_unveil_command", "bool", false),
+		"unveil_allow":         hclspec.NewAttr("unveil_allow", "map(string)", false),
+		"unveil_deny":          hclspec.NewAttr("unveil_deny", "list(string)", false),
+		"allow_user_namespace": hclspec.NewAttr("allow_user_namespace", "bool", false),
+		"users":                hclspec.NewAttr("users", "list(string)", false),
+		"rlimits_by_task":      hclspec.NewAttr("rlimits_by_task", "bool", false),
+		"rlimits_max":          hclspec.NewAttr("rlimits_max", "map(string)", false),
+		"oom_score_adj_max":    hclspec.NewAttr("oom_score_adj_max", "number", false),
+		"nice_min":             hclspec.NewAttr("nice_min", "number", false),
+		"io_priority_min":      hclspec.NewAttr("io_priority_min", "number", false),
+		"cpu_weight_max":       hclspec.NewAttr("cpu_weight_max", "number", false),
+	})),
 })
 
 // taskConfigSpec is the HCL configuration set for the task on the jobspec
 var taskConfigSpec = hclspec.NewObject(map[string]*hclspec.Spec{
-	"command":       hclspec.NewAttr("command", "string", true),
-	"args":          hclspec.NewAttr("args", "list(string)", false),
-	"unveil":        hclspec.NewAttr("unveil", "list(string)", false),
-	"oom_score_adj": hclspec.NewAttr("oom_score_adj", "number", false),
+	"command":         hclspec.NewAttr("command", "string", true),
+	"args":            hclspec.NewAttr("args", "list(string)", false),
+	"unveil":          hclspec.NewAttr("unveil", "list(string)", false),
+	"unveil_profiles": hclspec.NewAttr("unveil_profiles", "list(string)", false),
+	"oom_score_adj":   hclspec.NewAttr("oom_score_adj", "number", false),
+	"uts":             hclspec.NewAttr("uts", "bool", false),
+	"hostname":        hclspec.NewAttr("hostname", "string", false),
+	"user_namespace":  hclspec.NewAttr("user_namespace", "bool", false),
+	"tmpfs_size":      hclspec.NewAttr("tmpfs_size", "number", false),
+	"tmpfs_tmpdir":    hclspec.NewAttr("tmpfs_tmpdir", "bool", false),
+	"proc":            hclspec.NewAttr("proc", "string", false),
+	"rlimits":         hclspec.NewAttr("rlimits", "map(string)", false),
+	"nice":            hclspec.NewAttr("nice", "number", false),
+	"sched_policy":    hclspec.NewAttr("sched_policy", "string", false),
+	"io_class":        hclspec.NewAttr("io_class", "string", false),
+	"io_priority":     hclspec.NewAttr("io_priority", "number", false),
+	"cpu_idle":        hclspec.NewAttr("cpu_idle", "bool", false),
+	"cpu_weight":      hclspec.NewAttr("cpu_weight", "number", false),
+	"cpu_period":      hclspec.NewAttr("cpu_period", "number", false),
+	"cpu_burst":       hclspec.NewAttr("cpu_burst", "number", false),
+	"memory_high":     hclspec.NewAttr("memory_high", "string", false),
+	"memory_min":      hclspec.NewAttr("memory_min", "number", false),
+	"swap":            hclspec.NewAttr("swap", "string", false),
+	"numa":            hclspec.NewAttr("numa", "string", false),
 })
 
 var capabilities = &drivers.Capabilities{
@@ -73,6 +162,7 @@ var capabilities = &drivers.Capabilities{
 		drivers.NetIsolationModeNone,
 		drivers.NetIsolationModeHost,
 		drivers.NetIsolationModeGroup,
+		drivers.NetIsolationModeTask,
 	},
 }
 
@@ -82,6 +172,60 @@ type Config struct {
 	UnveilDefaults bool     `codec:"unveil_defaults"`
 	UnveilPaths    []string `codec:"unveil_paths"`
 	UnveilByTask   bool     `codec:"unveil_by_task"`
+
+	// AutoUnveilCommand is whether the command of tasks, and the interpreter
+	// and libraries it needs, are unveiled automatically.
+	AutoUnveilCommand bool `codec:"auto_unveil_command"`
+
+	// UnveilAllow maps the path prefixes tasks may unveil to the maximum mode
+	// under each, and UnveilDeny are paths tasks may never unveil.
+	UnveilAllow map[string]string `codec:"unveil_allow"`
+	UnveilDeny  []string          `codec:"unveil_deny"`
+
+	// Landlock is the enforcement of the landlock rights of tasks, either
+	// mandatory or best-effort on kernels which cannot restrict every right.
+	Landlock string `codec:"landlock"`
+
+	AllowUserNamespace bool `codec:"allow_user_namespace"`
+	InitiateNetwork    bool `codec:"initiate_network"`
+
+	// Proc is the minimum hardening of the /proc filesystem of tasks.
+	Proc string `codec:"proc"`
+
+	// Rlimits are the default resource limits of tasks, RlimitsMax are the
+	// largest hard limits tasks may set, and RlimitsByTask whether tasks may
+	// set any.
+	Rlimits       map[string]string `codec:"rlimits"`
+	RlimitsMax    map[string]string `codec:"rlimits_max"`
+	RlimitsByTask bool              `codec:"rlimits_by_task"`
+
+	// Users are patterns of the users tasks may run as, if not any user.
+	Users []string `codec:"users"`
+
+	// OOMScoreAdjMax is the largest oom_score_adj tasks may set.
+	OOMScoreAdjMax int `codec:"oom_score_adj_max"`
+
+	// NiceMin and IOPriorityMin are the most favorable nice value and best
+	// effort io priority tasks may set, and CPUWeightMax the largest cpu.weight.
+	NiceMin       int `codec:"nice_min"`
+	IOPriorityMin int `codec:"io_priority_min"`
+	CPUWeightMax  int `codec:"cpu_weight_max"`
+
+	// Swap is the default swap allowance of tasks.
+	Swap string `codec:"swap"`
+
+	// Profiles are named sets of unveil paths tasks may opt into.
+	Profiles map[string]*Profile `codec:"profile"`
+
+	// Policies are named blocks of config applying to the tasks of matching
+	// namespaces and jobs.
+	Policies map[string]*Policy `codec:"policy"`
+}
+
+// Profile is a named set of unveil paths defined in plugin config, which a
+// task opts into through unveil_profiles.
+type Profile struct {
+	Unveil []string `codec:"unveil"`
 }
 
 // TaskConfig represents the exec2 driver task configuration that gets set in
@@ -90,5 +234,31 @@ type TaskConfig struct {
 	Command     string   `codec:"command"`
 	Args        []string `codec:"args"`
 	Unveil      []string `codec:"unveil"`
+	Profiles    []string `codec:"unveil_profiles"`
 	OOMScoreAdj int      `codec:"oom_score_adj"`
+	UTS         bool     `codec:"uts"`
+	Hostname    string   `codec:"hostname"`
+
+	UserNamespace bool `codec:"user_namespace"`
+
+	TmpfsSize   int  `codec:"tmpfs_size"`
+	TmpfsTmpDir bool `codec:"tmpfs_tmpdir"`
+
+	Proc    string            `codec:"proc"`
+	Rlimits map[string]string `codec:"rlimits"`
+
+	Nice        int    `codec:"nice"`
+	SchedPolicy string `codec:"sched_policy"`
+	IOClass     string `codec:"io_class"`
+	IOPriority  *int   `codec:"io_priority"`
+	CPUIdle     bool   `codec:"cpu_idle"`
+	CPUWeight   int    `codec:"cpu_weight"`
+	CPUPeriod   int    `codec:"cpu_period"`
+	CPUBurst    int    `codec:"cpu_burst"`
+
+	MemoryHigh string `codec:"memory_high"`
+	MemoryMin  int    `codec:"memory_min"`
+	Swap       string `codec:"swap"`
+
+	NUMA string `codec:"numa"`
 }
@@ -7,22 +7,31 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/nomad-driver-exec2/pkg/netns"
 	"github.com/hashicorp/nomad-driver-exec2/pkg/resources"
 	"github.com/hashicorp/nomad-driver-exec2/pkg/shim"
 	"github.com/hashicorp/nomad-driver-exec2/pkg/task"
 	"github.com/hashicorp/nomad-driver-exec2/pkg/util"
+	"github.com/hashicorp/nomad/client/lib/cgroupslib"
 	"github.com/hashicorp/nomad/client/lib/cpustats"
 	cstructs "github.com/hashicorp/nomad/client/structs"
 	"github.com/hashicorp/nomad/drivers/shared/eventer"
+	"github.com/hashicorp/nomad/drivers/shared/resolvconf"
 	"github.com/hashicorp/nomad/helper"
+	"github.com/hashicorp/nomad/helper/pointer"
 	"github.com/hashicorp/nomad/plugins/base"
+	"github.com/hashicorp/nomad/plugins/device"
 	"github.com/hashicorp/nomad/plugins/drivers"
 	"github.com/hashicorp/nomad/plugins/drivers/utils"
 	"github.com/hashicorp/nomad/plugins/shared/hclspec"
@@ -92,11 +101,36 @@ func (p *Plugin) SetConfig(c *base.Config) error {
 	p.compute = c.AgentConfig.Compute()
 	resources.SetSpecs(p.compute)
 
+	// validate the decoded config object
+	if _, err := procMode(config.Proc); err != nil {
+		return err
+	}
+	if err := validateRlimits(&config); err != nil {
+		return err
+	}
+	if _, err := swapMax(config.Swap); err != nil {
+		return err
+	}
+	if err := validateUnveilPolicy(&config); err != nil {
+		return err
+	}
+	if err := validateOOMScoreAdjMax(config.OOMScoreAdjMax); err != nil {
+		return err
+	}
+	if err := validatePolicies(&config); err != nil {
+		return err
+	}
+	switch config.Landlock {
+	case "":
+		config.Landlock = landlockMandatory
+	case landlockMandatory, landlockBestEffort:
+	default:
+		return fmt.Errorf("landlock must be %q or %q, got %q", landlockMandatory, landlockBestEffort, config.Landlock)
+	}
+
 	// Set the decoded config object
 	p.config = &config
 
-	// currently not much to validate on the plugin config, but if there was
-	// that step would go here
 	return nil
 }
 
@@ -104,8 +138,52 @@ func (*Plugin) TaskConfigSchema() (*hclspec.Spec, error) {
 	return taskConfigSpec, nil
 }
 
-func (*Plugin) Capabilities() (*drivers.Capabilities, error) {
-	return capabilities, nil
+func (p *Plugin) Capabilities() (*drivers.Capabilities, error) {
+	// the driver creates group network namespaces itself only if configured
+	// to do so, as only one driver of a group may initiate the network
+	caps := *capabilities
+	caps.MustInitiateNetwork = p.config.InitiateNetwork
+	return &caps, nil
+}
+
+// CreateNetwork creates the network namespace of an allocation. Only called by
+// Nomad if the plugin is configured with initiate_network.
+func (p *Plugin) CreateNetwork(allocID string, _ *drivers.NetworkCreateRequest) (*drivers.NetworkIsolationSpec, bool, error) {
+	path := netns.Path(allocID)
+	spec := &drivers.NetworkIsolationSpec{
+		Mode:   drivers.NetIsolationModeGroup,
+		Path:   path,
+		Labels: make(map[string]string),
+	}
+
+	// when the client restarts, the namespace will already exist and be in
+	// use by the tasks of the allocation
+	if netns.Exists(path) {
+		p.logger.Debug("recovered network namespace", "alloc_id", allocID, "path", path)
+		return spec, false, nil
+	}
+
+	// otherwise remove any stale mount point and (re)create the namespace
+	if err := netns.Destroy(path); err != nil {
+		return nil, false, err
+	}
+	if _, err := netns.Create(allocID); err != nil {
+		p.logger.Error("failed to create network namespace", "alloc_id", allocID, "error", err)
+		return nil, false, err
+	}
+
+	p.logger.Debug("created network namespace", "alloc_id", allocID, "path", path)
+	return spec, true, nil
+}
+
+// DestroyNetwork removes the network namespace of an allocation created by
+// CreateNetwork.
+func (p *Plugin) DestroyNetwork(allocID string, spec *drivers.NetworkIsolationSpec) error {
+	if spec == nil {
+		return nil
+	}
+	p.logger.Debug("destroy network namespace", "alloc_id", allocID, "path", spec.Path)
+	return netns.Destroy(spec.Path)
 }
 
 func (p *Plugin) Fingerprint(ctx context.Context) (<-chan *drivers.Fingerprint, error) {
@@ -167,13 +245,32 @@ func (p *Plugin) doFingerprint(find lookupFunc) *drivers.Fingerprint {
 		return failure(drivers.HealthStateUndetected, "unshare executable does not exist")
 	}
 
+	// inspect landlock, without which tasks cannot be isolated, and which
+	// must restrict every right unless enforcement is best-effort
+	abi, err := landlockABI()
+	if err != nil {
+		return failure(drivers.HealthStateUndetected, "landlock is not available")
+	}
+	if missing := shim.Unenforced(abi, true, nil); missing != 0 && p.config.Landlock == landlockMandatory {
+		return failure(drivers.HealthStateUnhealthy, fmt.Sprintf("landlock ABI %d cannot restrict %s", abi, missing))
+	}
+
+	// tasks may only use a user namespace if allowed, and if the node lets an
+	// unprivileged user create one
+	userns := p.config.AllowUserNamespace && userNamespaces(nPath, uPath)
+
 	// create our fingerprint
 	return &drivers.Fingerprint{
 		Health:            drivers.HealthStateHealthy,
 		HealthDescription: drivers.DriverHealthy,
 		Attributes: map[string]*structs.Attribute{
-			"driver.exec2.unveil.tasks":    structs.NewBoolAttribute(p.config.UnveilByTask),
-			"driver.exec2.unveil.defaults": structs.NewBoolAttribute(p.config.UnveilDefaults),
+			"driver.exec2.unveil.tasks":         structs.NewBoolAttribute(p.config.UnveilByTask),
+			"driver.exec2.unveil.defaults":      structs.NewBoolAttribute(p.config.UnveilDefaults),
+			"driver.exec2.user_namespace":       structs.NewBoolAttribute(userns),
+			"driver.exec2.swap_accounting":      structs.NewBoolAttribute(swapAccounting()),
+			"driver.exec2.cpu_burst":            structs.NewBoolAttribute(cpuBurst()),
+			"driver.exec2.landlock.abi":         structs.NewIntAttribute(int64(abi), ""),
+			"driver.exec2.landlock.best_effort": structs.NewBoolAttribute(p.config.Landlock == landlockBestEffort),
 		},
 	}
 }
@@ -218,17 +315,6 @@ func (p *Plugin) StartTask(config *drivers.TaskConfig) (*drivers.TaskHandle, *dr
 	memory := uint64(config.Resources.NomadResources.Memory.MemoryMB) * 1024 * 1024
 	memoryMax := uint64(config.Resources.NomadResources.Memory.MemoryMaxMB) * 1024 * 1024
 
-	// compute cpu bandwidth value
-	bandwidth, err := resources.Bandwidth(uint64(config.Resources.NomadResources.Cpu.CpuShares))
-	if err != nil {
-		p.logger.Error("failed to compute cpu bandwidth", "error", err)
-		return nil, nil, fmt.Errorf("failed to compute cpu bandwidth: %w", err)
-	}
-
-	// get our assigned cpuset cores
-	cpuset := config.Resources.LinuxResources.CpusetCpus
-	p.logger.Trace("resources", "memory", memory, "memory_max", memoryMax, "compute", bandwidth, "cpuset", cpuset)
-
 	// with cgroups v2 this is just the task cgroup
 	cgroup := config.Resources.LinuxResources.CpusetCgroupPath
 
@@ -246,6 +332,51 @@ func (p *Plugin) StartTask(config *drivers.TaskConfig) (*drivers.TaskHandle, *dr
 		return nil, nil, err
 	}
 
+	// compute cpu bandwidth value, within the cpu period of the task
+	bandwidth, err := resources.Bandwidth(uint64(config.Resources.NomadResources.Cpu.CpuShares), opts.CPUPeriod)
+	if err != nil {
+		p.logger.Error("failed to compute cpu bandwidth", "error", err)
+		return nil, nil, fmt.Errorf("failed to compute cpu bandwidth: %w", err)
+	}
+
+	// get our assigned cpuset cores
+	cpuset := config.Resources.LinuxResources.CpusetCpus
+	p.logger.Trace("resources", "memory", memory, "memory_max", memoryMax, "compute", bandwidth, "cpuset", cpuset)
+
+	// set the mounts made inside the task mount namespace
+	mounts, err := p.mounts(config, opts)
+	if err != nil {
+		p.logger.Error("failed to setup task mounts", "error", err)
+		return nil, nil, err
+	}
+
+	// check the unveil paths and command, now the paths made for the task
+	// exist, so mistakes are reported rather than failing in the shim
+	if err = validateUnveil(config, opts); err != nil {
+		p.logger.Error("invalid unveil paths", "error", err)
+		p.emitEvent(config, fmt.Sprintf("Failed to start task: %v", err))
+		return nil, nil, err
+	}
+
+	// each path an unveil exclusion is expanded into is unveiled separately,
+	// which for large directories slows down starting the task
+	if n := unveilExpansion(opts); n > maxUnveilExpansion {
+		p.logger.Warn("unveil exclusions expand to many paths", "paths", n)
+		p.emitEvent(config, fmt.Sprintf("Unveil exclusions expand to %d paths", n))
+	}
+
+	// with best-effort enforcement, report the rights the kernel cannot
+	// restrict, which the task is not isolated from
+	landlock := landlockMandatory
+	if opts.BestEffort {
+		landlock = landlockBestEffort
+		abi, _ := landlockABI()
+		if missing := shim.Unenforced(abi, opts.UnveilDefaults, opts.UnveilPaths); missing != 0 {
+			p.logger.Warn("landlock enforcement is best-effort", "abi", abi, "unrestricted", missing.String())
+			p.emitEvent(config, fmt.Sprintf("Landlock enforcement is best-effort: ABI %d cannot restrict %s", abi, missing))
+		}
+	}
+
 	// set the task execution environment
 	// no task logging yet; that is setup in the shim
 	env := &shim.Environment{
@@ -254,12 +385,14 @@ func (p *Plugin) StartTask(config *drivers.TaskConfig) (*drivers.TaskHandle, *dr
 		Env:          config.Env,
 		TaskDir:      config.TaskDir().Dir,
 		User:         config.User,
+		Home:         taskHome(config),
 		Cgroup:       cgroup,
-		Net:          netns(config),
+		Net:          netnsPath(config),
 		Memory:       memory,
 		MemoryMax:    memoryMax,
 		CPUBandwidth: bandwidth,
 		OOMScoreAdj:  opts.OOMScoreAdj,
+		Mounts:       mounts,
 	}
 
 	// what is about to happen
@@ -270,20 +403,44 @@ func (p *Plugin) StartTask(config *drivers.TaskConfig) (*drivers.TaskHandle, *dr
 		"unveil_paths", opts.UnveilPaths,
 		"unveil_defaults", opts.UnveilDefaults,
 		"oom_score_adj", opts.OOMScoreAdj,
+		"hostname", opts.Hostname,
+		"user_namespace", opts.UserNamespace,
+		"tmpfs_size", opts.TmpfsSize,
+		"proc", opts.Proc,
+		"rlimits", opts.Rlimits,
+		"sched", opts.Sched,
+		"cpu_idle", opts.CPUIdle,
+		"cpu_weight", opts.CPUWeight,
+		"cpu_period", opts.CPUPeriod,
+		"cpu_burst", opts.CPUBurst,
+		"memory_high", opts.MemoryHigh,
+		"memory_min", opts.MemoryMin,
+		"swap_max", opts.SwapMax,
+		"cpuset_mems", opts.CpusetMems,
 	)
 
+	// create the private network namespace if the task is isolated on its own
+	if isolation(config) == drivers.NetIsolationModeTask {
+		if _, err = netns.Create(taskNetNS(config.ID)); err != nil {
+			p.logger.Error("failed to create task network namespace", "error", err)
+			return nil, nil, err
+		}
+	}
+
 	// create the runner and start it
 	runner := shim.New(env, opts)
 	if err = runner.Start(p.ctx); err != nil {
+		p.destroyTaskNetwork(config.ID)
 		return nil, nil, fmt.Errorf("failed to start task: %w", err)
 	}
 
 	// create and store a handle for the runner we just started
-	h, started := task.NewHandle(runner, config)
+	h, started := task.NewHandle(runner, config, landlock)
 	state := &task.State{
 		PID:        runner.PID(),
 		TaskConfig: config,
 		StartedAt:  started,
+		Landlock:   landlock,
 	}
 	if err = handle.SetDriverState(state); err != nil {
 		return nil, nil, fmt.Errorf("failed to set driver state: %w", err)
@@ -293,6 +450,63 @@ func (p *Plugin) StartTask(config *drivers.TaskConfig) (*drivers.TaskHandle, *dr
 	return handle, nil, nil
 }
 
+// emitEvent emits a task event with the given message.
+func (p *Plugin) emitEvent(config *drivers.TaskConfig, message string) {
+	event := &drivers.TaskEvent{
+		TaskID:    config.ID,
+		TaskName:  config.Name,
+		AllocID:   config.AllocID,
+		Timestamp: time.Now(),
+		Message:   message,
+	}
+	if err := p.events.EmitEvent(event); err != nil {
+		p.logger.Warn("failed to emit task event", "error", err)
+	}
+}
+
+// mounts returns the mounts to make inside the private mount namespace of the
+// task, before the task user is assumed.
+func (p *Plugin) mounts(config *drivers.TaskConfig, opts *shim.Options) ([]shim.Mount, error) {
+	var mounts []shim.Mount
+
+	// generate a resolv.conf from the dns block of the job network, and mount
+	// it in place of the one on the host
+	if config.DNS != nil {
+		dns, err := resolvconf.GenerateDNSMount(config.TaskDir().Dir, config.DNS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate resolv.conf: %w", err)
+		}
+		mounts = append(mounts, shim.BindMount(dns.HostPath, dns.TaskPath, dns.Readonly)...)
+	}
+
+	// write the hostname of the task, and mount it in place of the one on the
+	// host so /etc/hostname agrees with the uts namespace
+	if opts.Hostname != "" && exists(etcHostname) {
+		path := filepath.Join(config.TaskDir().Dir, "hostname")
+		if err := os.WriteFile(path, []byte(opts.Hostname+"\n"), 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write hostname file: %w", err)
+		}
+		mounts = append(mounts, shim.BindMount(path, etcHostname, true)...)
+	}
+
+	// replace passwd and group with files including a dynamic workload user
+	userMounts, err := users(config)
+	if err != nil {
+		return nil, err
+	}
+	mounts = append(mounts, userMounts...)
+
+	return mounts, nil
+}
+
+// etcHostname is the file containing the hostname of the system
+const etcHostname = "/etc/hostname"
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
 // RecoverTask will re-create the in-memory state of a task from a TaskHandle
 // coming from Nomad.
 func (p *Plugin) RecoverTask(handle *drivers.TaskHandle) error {
@@ -328,7 +542,7 @@ func (p *Plugin) RecoverTask(handle *drivers.TaskHandle) error {
 
 	// re-establish task handle by locating the unix process of the PID
 	runner := shim.Recover(taskState.PID, env)
-	recHandle := task.RecreateHandle(runner, taskState.TaskConfig, taskState.StartedAt)
+	recHandle := task.RecreateHandle(runner, taskState.TaskConfig, taskState.StartedAt, taskState.Landlock)
 	p.tasks.Set(taskState.TaskConfig.ID, recHandle)
 	return nil
 }
@@ -394,9 +608,18 @@ func (p *Plugin) DestroyTask(taskID string, force bool) error {
 	}
 
 	p.tasks.Del(taskID)
+	p.destroyTaskNetwork(taskID)
 	return err
 }
 
+// destroyTaskNetwork removes the private network namespace of the task, if
+// one was created.
+func (p *Plugin) destroyTaskNetwork(taskID string) {
+	if err := netns.Destroy(netns.Path(taskNetNS(taskID))); err != nil {
+		p.logger.Warn("failed to destroy task network namespace", "id", taskID, "error", err)
+	}
+}
+
 // InspectTask returns status information for the task associated with the
 // given taskID.
 func (p *Plugin) InspectTask(taskID string) (*drivers.TaskStatus, error) {
@@ -414,17 +637,21 @@ func (p *Plugin) TaskStats(ctx context.Context, taskID string, interval time.Dur
 	if !exists {
 		return nil, nil
 	}
+	// the memory.events counts are only of interest when memory_high is set
+	var taskConfig TaskConfig
+	if err := h.Config().DecodeDriverConfig(&taskConfig); err != nil {
+		return nil, err
+	}
+
 	ch := make(chan *drivers.TaskResourceUsage)
-	go p.stats(ctx, ch, interval, h)
+	go p.stats(ctx, ch, interval, h, taskConfig.MemoryHigh != "")
 	return ch, nil
 }
 
-// TaskEvents produces an empty chan of TaskEvents.
-func (*Plugin) TaskEvents(ctx context.Context) (<-chan *drivers.TaskEvent, error) {
-	// currently the exec2 driver does not produce any task events
-	// (an example usage would be like downloading an image, etc.)
-	ch := make(chan *drivers.TaskEvent, 1)
-	return ch, nil
+// TaskEvents returns a chan of TaskEvents emitted by the driver, e.g. when the
+// memory usage of a task is throttled.
+func (p *Plugin) TaskEvents(ctx context.Context) (<-chan *drivers.TaskEvent, error) {
+	return p.events.TaskEvents(ctx)
 }
 
 // SignalTask will use the kill() syscall to send signal to the unix process
@@ -452,23 +679,50 @@ func (*Plugin) ExecTaskStreaming(ctx context.Context, taskID string, execOptions
 	return nil, errors.New("ExecTaskStreaming is not yet implemented")
 }
 
-// netns returns the filepath to the network namespace if the network
-// isolation mode is set to bridge
-func netns(c *drivers.TaskConfig) string {
-	const none = ""
+// isolation returns the network isolation mode of the task.
+//
+// Tasks using the "driver" network mode are isolated by task, which Nomad
+// may only express through the network resources of the task itself. A group
+// network in "driver" mode reaches the driver as a group isolation spec, for
+// a namespace shared by every task of the allocation.
+func isolation(c *drivers.TaskConfig) drivers.NetIsolationMode {
 	switch {
 	case c == nil:
-		return none
-	case c.NetworkIsolation == nil:
-		return none
-	case c.NetworkIsolation.Mode == drivers.NetIsolationModeGroup:
+		return drivers.NetIsolationModeHost
+	case c.NetworkIsolation != nil:
+		return c.NetworkIsolation.Mode
+	case c.Resources == nil || c.Resources.NomadResources == nil:
+		return drivers.NetIsolationModeHost
+	}
+	for _, network := range c.Resources.NomadResources.Networks {
+		if network.Mode == "driver" {
+			return drivers.NetIsolationModeTask
+		}
+	}
+	return drivers.NetIsolationModeHost
+}
+
+// taskNetNS returns the name of the private network namespace created for
+// a task using the task network isolation mode.
+func taskNetNS(taskID string) string {
+	return "exec2-" + strings.ReplaceAll(taskID, "/", "-")
+}
+
+// netnsPath returns the filepath to the network namespace if the network
+// isolation mode is set to bridge (group) or task
+func netnsPath(c *drivers.TaskConfig) string {
+	const none = ""
+	switch isolation(c) {
+	case drivers.NetIsolationModeGroup:
 		return c.NetworkIsolation.Path
+	case drivers.NetIsolationModeTask:
+		return netns.Path(taskNetNS(c.ID))
 	default:
 		return none
 	}
 }
 
-func (p *Plugin) stats(ctx context.Context, ch chan<- *drivers.TaskResourceUsage, interval time.Duration, h *task.Handle) {
+func (p *Plugin) stats(ctx context.Context, ch chan<- *drivers.TaskResourceUsage, interval time.Duration, h *task.Handle, high bool) {
 	defer close(ch)
 
 	// Nomad client asks for 1 second intervals. Our handle will cache results
@@ -487,6 +741,21 @@ func (p *Plugin) stats(ctx context.Context, ch chan<- *drivers.TaskResourceUsage
 		}
 
 		usage := h.Stats()
+		now := time.Now().UTC()
+
+		// let the user know the task is being throttled, as it may be
+		// running much slower than expected
+		if h.Throttled(usage.MemoryEvents.High) {
+			message := "Task memory usage exceeded memory_high and is being throttled"
+			if err := p.events.EmitEvent(h.Event(message)); err != nil {
+				p.logger.Warn("failed to emit task event", "error", err)
+			}
+		}
+
+		devices := blockStats(usage.IO, now)
+		if high {
+			devices = append(devices, memoryEventStats(usage.MemoryEvents, now))
+		}
 
 		ch <- &drivers.TaskResourceUsage{
 			ResourceUsage: &cstructs.ResourceUsage{
@@ -501,12 +770,13 @@ func (p *Plugin) stats(ctx context.Context, ch chan<- *drivers.TaskResourceUsage
 					SystemMode:       float64(usage.System),
 					Percent:          float64(usage.Percent),
 					TotalTicks:       float64(usage.Ticks),
-					ThrottledPeriods: 0,
-					ThrottledTime:    0,
-					Measured:         []string{"System Mode", "User Mode", "Percent"},
+					ThrottledPeriods: usage.ThrottlePeriods,
+					ThrottledTime:    usage.ThrottleTime,
+					Measured:         []string{"System Mode", "User Mode", "Percent", "Throttled Periods", "Throttled Time"},
 				},
+				DeviceStats: devices,
 			},
-			Timestamp: time.Now().UTC().UnixNano(),
+			Timestamp: now.UnixNano(),
 			Pids:      nil,
 		}
 
@@ -515,41 +785,668 @@ func (p *Plugin) stats(ctx context.Context, ch chan<- *drivers.TaskResourceUsage
 	}
 }
 
+func counter(v uint64, unit, desc string) *structs.StatValue {
+	return &structs.StatValue{IntNumeratorVal: pointer.Of(int64(v)), Unit: unit, Desc: desc}
+}
+
+func gauge(v float64, unit, desc string) *structs.StatValue {
+	return &structs.StatValue{FloatNumeratorVal: pointer.Of(v), Unit: unit, Desc: desc}
+}
+
+// memoryEventStats converts the memory.events counts of the task cgroup into
+// stats reported alongside those of devices, as Nomad has no place for them
+// otherwise, with the memory.high throttling count as the summary.
+func memoryEventStats(events resources.MemoryEvents, now time.Time) *device.DeviceGroupStats {
+	return &device.DeviceGroupStats{
+		Vendor: "cgroup",
+		Type:   "memory.events",
+		Name:   "memory_high",
+		InstanceStats: map[string]*device.DeviceStats{
+			"memory.events": {
+				Summary: counter(events.High, "", "Times memory usage exceeded memory.high"),
+				Stats: &structs.StatObject{
+					Attributes: map[string]*structs.StatValue{
+						"low":      counter(events.Low, "", "Times memory usage below memory.low was reclaimed"),
+						"high":     counter(events.High, "", "Times memory usage exceeded memory.high"),
+						"max":      counter(events.Max, "", "Times memory usage was about to exceed memory.max"),
+						"oom":      counter(events.OOM, "", "Times memory usage reached memory.max"),
+						"oom_kill": counter(events.OOMKill, "", "Processes killed by the OOM killer"),
+					},
+				},
+				Timestamp: now,
+			},
+		},
+	}
+}
+
+// blockStats converts the io.stat accounting of the task cgroup into device
+// stats, with one instance per block device.
+func blockStats(ios []*resources.BlockIO, now time.Time) []*device.DeviceGroupStats {
+	if len(ios) == 0 {
+		return nil
+	}
+
+	instances := make(map[string]*device.DeviceStats, len(ios))
+	for _, io := range ios {
+		instances[io.Device] = &device.DeviceStats{
+			Summary: gauge(io.ReadBytesRate+io.WriteBytesRate, "B/s", "Bytes read and written per second"),
+			Stats: &structs.StatObject{
+				Attributes: map[string]*structs.StatValue{
+					"read_bytes":         counter(io.ReadBytes, "B", "Total bytes read"),
+					"write_bytes":        counter(io.WriteBytes, "B", "Total bytes written"),
+					"read_ios":           counter(io.ReadIOs, "", "Total read operations"),
+					"write_ios":          counter(io.WriteIOs, "", "Total write operations"),
+					"discard_bytes":      counter(io.DiscardBytes, "B", "Total bytes discarded"),
+					"read_bytes_rate":    gauge(io.ReadBytesRate, "B/s", "Bytes read per second"),
+					"write_bytes_rate":   gauge(io.WriteBytesRate, "B/s", "Bytes written per second"),
+					"read_ios_rate":      gauge(io.ReadIOsRate, "IOPS", "Read operations per second"),
+					"write_ios_rate":     gauge(io.WriteIOsRate, "IOPS", "Write operations per second"),
+					"discard_bytes_rate": gauge(io.DiscardBytesRate, "B/s", "Bytes discarded per second"),
+				},
+			},
+			Timestamp: now,
+		}
+	}
+
+	return []*device.DeviceGroupStats{{
+		Vendor:        "linux",
+		Type:          "block",
+		Name:          "io",
+		InstanceStats: instances,
+	}}
+}
+
 func (p *Plugin) setOptions(driverTaskConfig *drivers.TaskConfig) (*shim.Options, error) {
 	var taskConfig TaskConfig
 	if err := driverTaskConfig.DecodeDriverConfig(&taskConfig); err != nil {
 		return nil, fmt.Errorf("failed to decode driver task config: %w", err)
 	}
 
+	// the plugin config, with the policy matching the namespace or job of the
+	// task applied
+	config, _ := p.policy(driverTaskConfig)
+
+	// the task must run as a user allowed by the driver config
+	if err := checkUser(config, driverTaskConfig.User); err != nil {
+		return nil, err
+	}
+
 	// combine paths to unveil from plugin config, task config (if enabled),
 	// and some task/alloc directory default paths
-	unveil := slices.Clone(p.config.UnveilPaths)
+	unveil := slices.Clone(config.UnveilPaths)
 
 	// if the plugin config.unveil_defaults value is set to true (very common)
 	// then automatically unveil the sandbox directories
-	if p.config.UnveilDefaults {
+	if config.UnveilDefaults {
 		unveil = append(unveil, "rwxc:"+driverTaskConfig.Env["NOMAD_TASK_DIR"])
 		unveil = append(unveil, "rwxc:"+driverTaskConfig.Env["NOMAD_ALLOC_DIR"])
 		unveil = append(unveil, "rx:"+driverTaskConfig.Env["NOMAD_ALLOC_DIR"]+"/logs")
 		unveil = append(unveil, "rwxc:"+driverTaskConfig.Env["NOMAD_SECRETS_DIR"])
 		parent := filepath.Dir(driverTaskConfig.Env["NOMAD_TASK_DIR"])
 		unveil = append(unveil, "rwxc:"+filepath.Join(parent, "tmp"))
+
+		// the task cgroup is mounted at /sys/fs/cgroup in the task namespace
+		unveil = append(unveil, "r:/sys/fs/cgroup")
+	}
+
+	// a dynamic workload user gets a home directory, and passwd and group
+	// files containing its own entry
+	if home := taskHome(driverTaskConfig); home != "" && config.UnveilDefaults {
+		unveil = append(unveil, "rwxc:"+home)
+		for _, file := range []string{etcPasswd, etcGroup} {
+			if exists(file) {
+				unveil = append(unveil, "r:"+file)
+			}
+		}
+	}
+
+	// append the unveil paths of the profiles the task opts into, which are
+	// defined by the operator and so need not be allowed by unveil_by_task
+	for _, name := range taskConfig.Profiles {
+		profile, ok := config.Profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("task set unveil profile %q which is not defined in driver config", name)
+		}
+		unveil = append(unveil, profile.Unveil...)
 	}
 
 	if len(taskConfig.Unveil) > 0 {
-		if !p.config.UnveilByTask {
+		if !config.UnveilByTask {
 			// if task.config.unveil is set, the plugin config must allow it
 			return nil, fmt.Errorf("task set unveil paths but driver config does not allow this")
 		}
+		// the user specified unveil paths must be within the unveil policy
+		if err := checkUnveil(config, taskConfig.Unveil); err != nil {
+			return nil, err
+		}
 		// append the user specified unveil paths from task.config.unveil
 		unveil = append(unveil, taskConfig.Unveil...)
 	}
 
+	// unveil the command of the task, along with the interpreter and shared
+	// libraries it needs, which must not be denied by the unveil policy
+	if config.AutoUnveilCommand {
+		entries, err := commandUnveil(taskConfig.Command, driverTaskConfig.TaskDir().Dir, driverTaskConfig.Env)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unveil command %q: %w", taskConfig.Command, err)
+		}
+		if err = checkUnveil(&Config{UnveilDeny: config.UnveilDeny}, entries); err != nil {
+			return nil, err
+		}
+		unveil = append(unveil, entries...)
+	}
+
+	// if task.config.user_namespace is set, the plugin config must allow it
+	if taskConfig.UserNamespace && !config.AllowUserNamespace {
+		return nil, fmt.Errorf("task set user_namespace but driver config does not allow this")
+	}
+
+	// a task with a private /tmp may read and write it regardless of the
+	// unveil defaults, as it is not shared with the host
+	switch {
+	case taskConfig.TmpfsSize < 0:
+		return nil, fmt.Errorf("tmpfs_size must not be negative")
+	case taskConfig.TmpfsSize == 0 && taskConfig.TmpfsTmpDir:
+		return nil, fmt.Errorf("tmpfs_tmpdir requires tmpfs_size to be set")
+	case taskConfig.TmpfsSize > 0 && strings.HasPrefix(driverTaskConfig.AllocDir, "/tmp/"):
+		// e.g. nomad in -dev mode; the tmpfs would hide the alloc directory
+		return nil, fmt.Errorf("tmpfs_size cannot be used when the alloc directory is under /tmp")
+	case taskConfig.TmpfsSize > 0:
+		unveil = append(unveil, "rwc:/tmp")
+	}
+
+	// the /proc of the task is at least as hardened as the plugin requires
+	proc, err := taskProc(config, taskConfig.Proc)
+	if err != nil {
+		return nil, err
+	}
+
+	// if task.config.rlimits is set, the plugin config must allow it
+	if len(taskConfig.Rlimits) > 0 && !config.RlimitsByTask {
+		return nil, fmt.Errorf("task set rlimits but driver config does not allow this")
+	}
+
+	// the resource limits of the task are the plugin defaults overridden by
+	// the task config, bounded by the plugin maximums
+	rlimits, err := taskRlimits(config, taskConfig.Rlimits)
+	if err != nil {
+		return nil, err
+	}
+
+	// the cpu and io scheduling of the task, within the plugin bounds
+	sched, err := taskSched(config, &taskConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case taskConfig.OOMScoreAdj < 0 || taskConfig.OOMScoreAdj > 1000:
+		return nil, fmt.Errorf("oom_score_adj must be between 0 and 1000")
+	case taskConfig.OOMScoreAdj > config.OOMScoreAdjMax:
+		return nil, fmt.Errorf("oom_score_adj %d exceeds maximum of %d allowed by driver config", taskConfig.OOMScoreAdj, config.OOMScoreAdjMax)
+	}
+
+	switch {
+	case taskConfig.CPUWeight < 0 || taskConfig.CPUWeight > 10000:
+		return nil, fmt.Errorf("cpu_weight must be between 1 and 10000")
+	case taskConfig.CPUWeight > config.CPUWeightMax:
+		return nil, fmt.Errorf("cpu_weight %d exceeds maximum of %d allowed by driver config", taskConfig.CPUWeight, config.CPUWeightMax)
+	}
+
+	// the cpu period of the task, and the cpu time it may burst above its
+	// bandwidth within a period
+	period, burst, err := cpuBurstLimits(&taskConfig, driverTaskConfig.Resources)
+	if err != nil {
+		return nil, err
+	}
+
+	// the soft memory limits of the task, relative to its memory resources
+	memoryHigh, memoryMin, err := memoryLimits(&taskConfig, driverTaskConfig.Resources)
+	if err != nil {
+		return nil, err
+	}
+
+	// the swap allowance of the task, or the plugin default
+	swap, err := taskSwap(config, taskConfig.Swap)
+	if err != nil {
+		return nil, err
+	}
+
+	// the numa nodes the memory of the task is allocated from
+	mems, err := numaMems(taskConfig.NUMA, driverTaskConfig.Resources)
+	if err != nil {
+		return nil, err
+	}
+
+	// a task with its own uts namespace gets the given hostname, or one
+	// derived from the task name and alloc ID
+	var name string
+	if taskConfig.UTS || taskConfig.Hostname != "" {
+		name = taskConfig.Hostname
+		if name == "" {
+			name = hostname(driverTaskConfig.Name, driverTaskConfig.AllocID)
+		}
+		if !validHostname(name) {
+			return nil, fmt.Errorf("hostname %q is not a valid hostname", name)
+		}
+
+		// the default dns paths do not include /etc/hostname, which will be
+		// replaced with a file containing the task hostname
+		if config.UnveilDefaults && exists(etcHostname) {
+			unveil = append(unveil, "r:"+etcHostname)
+		}
+	}
+
 	return &shim.Options{
 		Command:        taskConfig.Command,
 		Arguments:      taskConfig.Args,
 		UnveilPaths:    unveil,
-		UnveilDefaults: p.config.UnveilDefaults,
+		UnveilDefaults: config.UnveilDefaults,
+		BestEffort:     config.Landlock == landlockBestEffort,
 		OOMScoreAdj:    taskConfig.OOMScoreAdj,
+		Hostname:       name,
+		UserNamespace:  taskConfig.UserNamespace,
+		TmpfsSize:      uint64(taskConfig.TmpfsSize) * 1024 * 1024,
+		TmpfsTmpDir:    taskConfig.TmpfsTmpDir,
+		Proc:           proc,
+		Rlimits:        rlimits,
+		Sched:          sched,
+		CPUIdle:        taskConfig.CPUIdle,
+		CPUWeight:      uint64(taskConfig.CPUWeight),
+		CPUPeriod:      period,
+		CPUBurst:       burst,
+		MemoryHigh:     memoryHigh,
+		MemoryMin:      memoryMin,
+		SwapMax:        swap,
+		CpusetMems:     mems,
+	}, nil
+}
+
+// cpuBurst reports whether the kernel supports cpu.max.burst, which is needed
+// for tasks to burst above their cpu bandwidth.
+var cpuBurst = func() bool {
+	_, err := cgroupslib.ReadNomadCG2("cpu.max.burst")
+	return err == nil
+}
+
+// cpuBurstLimits returns the cpu.max period and the cpu.max.burst value of a
+// task in microseconds. The period defaults to that of resources.Bandwidth,
+// and the burst is given in MHz and converted the same way as the cpu
+// resources of the task.
+func cpuBurstLimits(c *TaskConfig, r *drivers.Resources) (uint64, uint64, error) {
+	period := uint64(resources.DefaultPeriod)
+	switch {
+	case c.CPUPeriod == 0:
+	case c.CPUPeriod < 1000 || c.CPUPeriod > 1000000:
+		return 0, 0, fmt.Errorf("cpu_period must be between 1000 and 1000000 microseconds")
+	default:
+		period = uint64(c.CPUPeriod)
+	}
+
+	var mhz uint64
+	if r != nil && r.NomadResources != nil {
+		mhz = uint64(r.NomadResources.Cpu.CpuShares)
+	}
+
+	// the kernel does not allow a quota of less than 1ms per period
+	if period != resources.DefaultPeriod && mhz > 0 {
+		quota, err := resources.Bandwidth(mhz, period)
+		if err != nil {
+			return 0, 0, err
+		}
+		if quota < 1000 {
+			return 0, 0, fmt.Errorf("cpu_period of %d is too short for cpu of %d MHz", period, mhz)
+		}
+	}
+
+	switch {
+	case c.CPUBurst == 0:
+		return period, 0, nil
+	case c.CPUBurst < 0:
+		return 0, 0, fmt.Errorf("cpu_burst must not be negative")
+	case uint64(c.CPUBurst) > mhz:
+		return 0, 0, fmt.Errorf("cpu_burst of %d MHz exceeds cpu of %d MHz", c.CPUBurst, mhz)
+	case !cpuBurst():
+		return 0, 0, fmt.Errorf("cpu_burst requires cpu.max.burst, which is not supported on this node")
+	}
+
+	burst, err := resources.Bandwidth(uint64(c.CPUBurst), period)
+	if err != nil {
+		return 0, 0, err
+	}
+	return period, burst, nil
+}
+
+// memoryNodes returns the numa nodes of the given cpus.
+var memoryNodes = resources.MemoryNodes
+
+// numaMems returns the value of cpuset.mems for a task. By default (or with
+// "open") memory is allocated from any node, which the kernel already prefers
+// to be the node of the cpu touching it. With "strict" memory is allocated
+// only from the nodes of the cores reserved by the task.
+func numaMems(value string, r *drivers.Resources) (string, error) {
+	switch value {
+	case "", "open":
+		return "", nil
+	case "strict":
+	default:
+		return "", fmt.Errorf("numa must be \"strict\" or \"open\", got %q", value)
+	}
+
+	if r == nil || r.NomadResources == nil || r.LinuxResources == nil || len(r.NomadResources.Cpu.ReservedCores) == 0 {
+		return "", fmt.Errorf("numa of \"strict\" requires the task to reserve cores")
+	}
+
+	mems, err := memoryNodes(r.LinuxResources.CpusetCpus)
+	if err != nil {
+		return "", fmt.Errorf("failed to find numa nodes of cores: %w", err)
+	}
+	return mems, nil
+}
+
+// userNamespaces reports whether an unprivileged user can create a user
+// namespace mapping itself to root, the way the shim of a task with
+// user_namespace is launched. Sysctls and security modules of the node may
+// prevent this.
+var userNamespaces = func(nsenter, unshare string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return exec.CommandContext(ctx,
+		nsenter, "--no-fork", "--setuid=65534", "--setgid=65534", "--",
+		unshare, "--user", "--map-root-user", "--", unshare, "--version",
+	).Run() == nil
+}
+
+// swapAccounting reports whether the kernel accounts the swap usage of
+// cgroups, which is needed to limit the swap usage of tasks.
+var swapAccounting = func() bool {
+	_, err := cgroupslib.ReadNomadCG2("memory.swap.max")
+	return err == nil
+}
+
+// swapMax converts a swap config value into the value of memory.swap.max,
+// which is empty when swap is unlimited (the default of memory.swap.max).
+func swapMax(value string) (string, error) {
+	switch value {
+	case "", "unlimited":
+		return "", nil
+	case "disabled":
+		return "0", nil
+	}
+	size, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("swap must be \"disabled\", \"unlimited\", or a number of MiB, got %q", value)
+	}
+	return strconv.FormatUint(size*1024*1024, 10), nil
+}
+
+// taskSwap returns the value of memory.swap.max for a task, which may only
+// be limited if the node has swap accounting.
+func taskSwap(config *Config, value string) (string, error) {
+	if value == "" {
+		value = config.Swap
+	}
+	result, err := swapMax(value)
+	switch {
+	case err != nil:
+		return "", err
+	case result != "" && !swapAccounting():
+		return "", fmt.Errorf("swap of %q requires swap accounting, which is not enabled on this node", value)
+	}
+	return result, nil
+}
+
+// memoryLimits returns the memory.high and memory.min values of a task in
+// bytes. The memory_high value is either a number of MiB, or a percentage of
+// the memory limit of the task (i.e. memory_max if set, otherwise memory).
+func memoryLimits(c *TaskConfig, r *drivers.Resources) (uint64, uint64, error) {
+	var memory, limit uint64
+	if r != nil && r.NomadResources != nil {
+		memory = uint64(r.NomadResources.Memory.MemoryMB)
+		limit = max(memory, uint64(r.NomadResources.Memory.MemoryMaxMB))
+	}
+
+	var high uint64
+	if value := c.MemoryHigh; value != "" {
+		number, percent := strings.CutSuffix(value, "%")
+		n, err := strconv.ParseUint(number, 10, 64)
+		switch {
+		case err != nil || n == 0:
+			return 0, 0, fmt.Errorf("memory_high must be a positive number of MiB or a percentage, got %q", value)
+		case percent && n > 100:
+			return 0, 0, fmt.Errorf("memory_high must not exceed 100%%")
+		case percent:
+			high = limit * n / 100
+		default:
+			high = n
+		}
+		if high > limit {
+			return 0, 0, fmt.Errorf("memory_high of %d MiB exceeds memory limit of %d MiB", high, limit)
+		}
+	}
+
+	switch {
+	case c.MemoryMin < 0:
+		return 0, 0, fmt.Errorf("memory_min must not be negative")
+	case uint64(c.MemoryMin) > memory:
+		return 0, 0, fmt.Errorf("memory_min of %d MiB exceeds memory of %d MiB", c.MemoryMin, memory)
+	}
+
+	const mib = 1024 * 1024
+	return high * mib, uint64(c.MemoryMin) * mib, nil
+}
+
+// taskSched returns the cpu and io scheduling of a task, or nil if the task is
+// to inherit the scheduling of the Nomad agent.
+func taskSched(config *Config, c *TaskConfig) (*shim.Sched, error) {
+	if c.Nice == 0 && c.SchedPolicy == "" && c.IOClass == "" && c.IOPriority == nil {
+		return nil, nil
+	}
+
+	switch {
+	case c.Nice < -20 || c.Nice > 19:
+		return nil, fmt.Errorf("nice must be between -20 and 19")
+	case c.Nice < config.NiceMin:
+		return nil, fmt.Errorf("nice %d is below minimum of %d allowed by driver config", c.Nice, config.NiceMin)
+	}
+
+	if _, ok := shim.SchedPolicies[c.SchedPolicy]; c.SchedPolicy != "" && !ok {
+		return nil, fmt.Errorf("sched_policy must be one of \"other\", \"batch\", or \"idle\", got %q", c.SchedPolicy)
+	}
+
+	class := c.IOClass
+	if _, ok := shim.IOClasses[class]; class != "" && !ok {
+		return nil, fmt.Errorf("io_class must be one of \"best-effort\" or \"idle\", got %q", class)
+	}
+
+	var priority int
+	if c.IOPriority != nil {
+		// an io priority is only meaningful for the best effort class
+		priority = *c.IOPriority
+		switch {
+		case class == "":
+			class = "best-effort"
+		case class != "best-effort":
+			return nil, fmt.Errorf("io_priority requires io_class \"best-effort\"")
+		}
+		switch {
+		case priority < 0 || priority > 7:
+			return nil, fmt.Errorf("io_priority must be between 0 and 7")
+		case priority < config.IOPriorityMin:
+			return nil, fmt.Errorf("io_priority %d is below minimum of %d allowed by driver config", priority, config.IOPriorityMin)
+		}
+	}
+
+	return &shim.Sched{
+		Nice:       c.Nice,
+		Policy:     c.SchedPolicy,
+		IOClass:    class,
+		IOPriority: priority,
 	}, nil
 }
+
+// validateRlimits checks the default and maximum resource limits of the plugin
+// config can be parsed, and that each default is within its maximum.
+func validateRlimits(config *Config) error {
+	for name, value := range config.RlimitsMax {
+		if _, err := shim.ParseRlimit(name, value); err != nil {
+			return fmt.Errorf("invalid rlimits_max: %w", err)
+		}
+	}
+	for name, value := range config.Rlimits {
+		limit, err := shim.ParseRlimit(name, value)
+		if err != nil {
+			return fmt.Errorf("invalid rlimits: %w", err)
+		}
+		if err = checkRlimit(limit, config.RlimitsMax); err != nil {
+			return fmt.Errorf("invalid rlimits: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkRlimit returns an error if the hard limit of limit exceeds the one of
+// its maximum, if any.
+func checkRlimit(limit shim.Rlimit, maximums map[string]string) error {
+	value, exists := maximums[limit.Name]
+	if !exists {
+		return nil
+	}
+	maximum, err := shim.ParseRlimit(limit.Name, value)
+	if err != nil {
+		return err
+	}
+	if limit.Hard > maximum.Hard {
+		return fmt.Errorf("rlimit %s of %s exceeds maximum of %s", limit.Name, limit, value)
+	}
+	return nil
+}
+
+// inheritedRlimit returns the named resource limit inherited from the Nomad
+// agent, which applies to tasks unless set by the plugin or task.
+var inheritedRlimit = shim.InheritedRlimit
+
+// taskRlimits returns the resource limits of a task, ordered by name. Limits
+// set by neither the plugin nor the task are clamped to their maximum, if the
+// limit inherited from the Nomad agent exceeds it.
+func taskRlimits(config *Config, task map[string]string) ([]shim.Rlimit, error) {
+	values := make(map[string]string, len(config.Rlimits)+len(task))
+	maps.Copy(values, config.Rlimits)
+	maps.Copy(values, task)
+
+	for name, value := range config.RlimitsMax {
+		if _, exists := values[name]; exists {
+			continue
+		}
+		maximum, err := shim.ParseRlimit(name, value)
+		if err != nil {
+			return nil, err
+		}
+		inherited, err := inheritedRlimit(name)
+		if err != nil {
+			return nil, err
+		}
+		if inherited.Hard > maximum.Hard {
+			inherited.Soft = min(inherited.Soft, maximum.Hard)
+			inherited.Hard = maximum.Hard
+			values[name] = inherited.String()
+		}
+	}
+
+	result := make([]shim.Rlimit, 0, len(values))
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		limit, err := shim.ParseRlimit(name, values[name])
+		if err != nil {
+			return nil, err
+		}
+		if err = checkRlimit(limit, config.RlimitsMax); err != nil {
+			return nil, err
+		}
+		result = append(result, limit)
+	}
+	return result, nil
+}
+
+// validateOOMScoreAdjMax checks the largest oom_score_adj tasks may set is
+// within the range tasks may set it in.
+func validateOOMScoreAdjMax(value int) error {
+	if value < 0 || value > 1000 {
+		return fmt.Errorf("oom_score_adj_max must be between 0 and 1000, got %d", value)
+	}
+	return nil
+}
+
+// The enforcement of the landlock rights of tasks.
+const (
+	landlockMandatory  = "mandatory"
+	landlockBestEffort = "best-effort"
+)
+
+// landlockABI returns the landlock ABI version of the kernel.
+var landlockABI = shim.ABI
+
+// procModes are the modes of /proc, in increasing order of hardening
+var procModes = []string{shim.ProcDefault, shim.ProcHardened, shim.ProcPID}
+
+// procMode converts a proc config value into the shim mode of /proc, and its
+// level of hardening.
+func procMode(value string) (int, error) {
+	if value == "default" {
+		value = shim.ProcDefault
+	}
+	level := slices.Index(procModes, value)
+	if level == -1 {
+		return 0, fmt.Errorf("proc must be one of \"default\", \"hardened\", or \"pid\", got %q", value)
+	}
+	return level, nil
+}
+
+// taskProc returns the mode of /proc for a task, which is the more hardened
+// of the task and plugin configuration.
+func taskProc(config *Config, value string) (string, error) {
+	task, err := procMode(value)
+	if err != nil {
+		return "", err
+	}
+	plugin, _ := procMode(config.Proc) // validated in SetConfig
+	return procModes[max(task, plugin)], nil
+}
+
+// hostnameRe matches a hostname of dot separated labels, each of at most 63
+// characters
+var hostnameRe = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+
+// validHostname returns whether name is a valid, possibly fully qualified,
+// hostname of at most 253 characters.
+func validHostname(name string) bool {
+	return len(name) <= 253 && hostnameRe.MatchString(name)
+}
+
+// hostname derives a valid hostname from the task name and alloc ID, in the
+// form <task>-<short alloc ID>.
+func hostname(task, allocID string) string {
+	short := allocID
+	if len(short) > 8 {
+		short = short[:8]
+	}
+
+	// replace anything not valid in a hostname with a dash
+	task = strings.ToLower(task)
+	task = strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '-'
+	}, task)
+
+	// leave room for the alloc ID suffix
+	if maximum := 63 - len(short) - 1; len(task) > maximum {
+		task = task[:maximum]
+	}
+
+	task = strings.Trim(task, "-")
+	if task == "" {
+		return short
+	}
+	return task + "-" + short
+}
@@ -12,12 +12,20 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/hashicorp/nomad-driver-exec2/pkg/resources"
+	"github.com/hashicorp/nomad-driver-exec2/pkg/shim"
 	"github.com/hashicorp/nomad/ci"
 	"github.com/hashicorp/nomad/client/lib/cgroupslib"
+	"github.com/hashicorp/nomad/client/lib/cpustats"
+	"github.com/hashicorp/nomad/client/lib/numalib/hw"
 	ctests "github.com/hashicorp/nomad/client/testutil"
+	"github.com/hashicorp/nomad/helper/pluginutils/hclutils"
+	"github.com/hashicorp/nomad/helper/pointer"
 	"github.com/hashicorp/nomad/helper/testlog"
 	"github.com/hashicorp/nomad/helper/uuid"
 	"github.com/hashicorp/nomad/nomad/structs"
@@ -149,17 +157,25 @@ func TestFunctional_cases(t *testing.T) {
 		name string
 
 		// task config
-		user    string
-		command string
-		args    []string
-		unveil  []string
+		user     string
+		command  string
+		args     []string
+		unveil   []string
+		dns      *drivers.DNSConfig
+		hostname string
+		userns   bool
+		proc     string
+		rlimits  map[string]string
+		nice     int
 
 		// plugin config
 		unveilDefaults bool
 		unveilByTask   bool
 		unveilPaths    []string
+		allowUserns    bool
 
 		// expectations
+		startErr string
 		exp      *drivers.ExitResult
 		stdoutRe *regexp.Regexp
 		stderrRe *regexp.Regexp
@@ -190,15 +206,16 @@ func TestFunctional_cases(t *testing.T) {
 			stdoutRe:       regexp.MustCompile(`USER=root`),
 		},
 		// run 'cat /etc/passwd' with default unveil paths
-		// (e.g. not even root can access it)
+		// (e.g. not even root can access it, but a dynamic user can read
+		// the synthesized one)
 		{
 			name:           "read /etc/passwd as dynamic using defaults",
 			user:           "nomad-80000",
 			command:        "cat",
 			unveilDefaults: true,
 			args:           []string{"/etc/passwd"},
-			exp:            &drivers.ExitResult{ExitCode: 1},
-			stderrRe:       regexp.MustCompile(`cat: /etc/passwd: Permission denied`),
+			exp:            &drivers.ExitResult{ExitCode: 0},
+			stdoutRe:       regexp.MustCompile(`nomad-80000:x:80000:80000:nomad workload user:\S+/home:/bin/sh`),
 		},
 		{
 			name:           "read /etc/passwd as nobody using defaults",
@@ -228,7 +245,7 @@ func TestFunctional_cases(t *testing.T) {
 			unveilPaths:    []string{"r:/etc/passwd"},
 			args:           []string{"/etc/passwd"},
 			exp:            &drivers.ExitResult{ExitCode: 0},
-			stdoutRe:       regexp.MustCompile(`root:x:0:0:root:/root:/bin/bash`),
+			stdoutRe:       regexp.MustCompile(`root:x:0:0:root:/root:/usr/sbin/nologin`),
 		},
 		{
 			name:           "read /etc/passwd as nobody using custom paths via plugin",
@@ -261,7 +278,7 @@ func TestFunctional_cases(t *testing.T) {
 			unveil:         []string{"r:/etc/passwd"},
 			args:           []string{"/etc/passwd"},
 			exp:            &drivers.ExitResult{ExitCode: 0},
-			stdoutRe:       regexp.MustCompile(`root:x:0:0:root:/root:/bin/bash`),
+			stdoutRe:       regexp.MustCompile(`root:x:0:0:root:/root:/usr/sbin/nologin`),
 		},
 		{
 			name:           "read /etc/passwd as nobody using custom paths via task",
@@ -291,8 +308,7 @@ func TestFunctional_cases(t *testing.T) {
 			user:           "nomad-80000",
 			command:        "/usr/bin/doesnotexist",
 			unveilDefaults: true,
-			exp:            &drivers.ExitResult{ExitCode: 127},
-			stderrRe:       regexp.MustCompile(`failed to locate command "/usr/bin/doesnotexist": exec: "/usr/bin/doesnotexist": stat /usr/bin/doesnotexist: no such file or directory`),
+			startErr:       `unveil "/usr/bin/doesnotexist": path /usr/bin/doesnotexist does not exist or cannot be resolved`,
 		},
 		// try to execute non-executable file
 		{
@@ -311,21 +327,21 @@ func TestFunctional_cases(t *testing.T) {
 			user:           "nomad-80000",
 			command:        "/usr/bin/env",
 			unveilDefaults: false,
-			exp:            &drivers.ExitResult{ExitCode: 2},
+			startErr:       "is not unveiled with execute permission",
 		},
 		{
 			name:           "run 'env' as nobody without default paths",
 			user:           "nobody",
 			command:        "/usr/bin/env",
 			unveilDefaults: false,
-			exp:            &drivers.ExitResult{ExitCode: 2},
+			startErr:       "is not unveiled with execute permission",
 		},
 		{
 			name:           "run 'env' as root without default paths",
 			user:           "root",
 			command:        "/usr/bin/env",
 			unveilDefaults: false,
-			exp:            &drivers.ExitResult{ExitCode: 2},
+			startErr:       "is not unveiled with execute permission",
 		},
 		// write to task directory
 		{
@@ -355,7 +371,8 @@ func TestFunctional_cases(t *testing.T) {
 			args:           []string{"-c", "cp /etc/hosts ${NOMAD_SECRETS_DIR}"},
 			exp:            &drivers.ExitResult{ExitCode: 0},
 		},
-		// fail to write to task directory with no defaults
+		// fail to write to task directory with no defaults, as not even the
+		// shell is unveiled
 		{
 			name:           "write to task directory no defaults",
 			user:           "nomad-81000",
@@ -363,7 +380,7 @@ func TestFunctional_cases(t *testing.T) {
 			unveilDefaults: false,
 			unveilPaths:    []string{"r:/etc/hosts"},
 			args:           []string{"-c", "cp /etc/hosts ${NOMAD_TASK_DIR}"},
-			exp:            &drivers.ExitResult{ExitCode: 2},
+			startErr:       "is not unveiled with execute permission",
 		},
 		{
 			name:           "write to alloc directory no defaults",
@@ -372,7 +389,7 @@ func TestFunctional_cases(t *testing.T) {
 			unveilDefaults: false,
 			unveilPaths:    []string{"r:/etc/hosts"},
 			args:           []string{"-c", "cp /etc/hosts ${NOMAD_ALLOC_DIR}"},
-			exp:            &drivers.ExitResult{ExitCode: 2},
+			startErr:       "is not unveiled with execute permission",
 		},
 		{
 			name:           "write to secrets directory no defaults",
@@ -381,7 +398,7 @@ func TestFunctional_cases(t *testing.T) {
 			unveilDefaults: false,
 			unveilPaths:    []string{"r:/etc/hosts"},
 			args:           []string{"-c", "cp /etc/hosts ${NOMAD_SECRETS_DIR}"},
-			exp:            &drivers.ExitResult{ExitCode: 2},
+			startErr:       "is not unveiled with execute permission",
 		},
 		// dyanmic id
 		{
@@ -390,7 +407,7 @@ func TestFunctional_cases(t *testing.T) {
 			command:        "id",
 			unveilDefaults: true,
 			exp:            &drivers.ExitResult{ExitCode: 0},
-			stdoutRe:       regexp.MustCompile(`uid=89000 gid=89000 groups=89000`),
+			stdoutRe:       regexp.MustCompile(`uid=89000\(nomad-89000\) gid=89000\(nomad-89000\) groups=89000\(nomad-89000\)`),
 		},
 		{
 			name:           "id nobody",
@@ -408,6 +425,38 @@ func TestFunctional_cases(t *testing.T) {
 			exp:            &drivers.ExitResult{ExitCode: 0},
 			stdoutRe:       regexp.MustCompile(`uid=0 gid=0 groups=0`),
 		},
+		// lower the resource limits of the task
+		{
+			name:           "rlimits",
+			user:           "nomad-80000",
+			command:        "sh",
+			args:           []string{"-c", "ulimit -Sn; ulimit -Hn; ulimit -c"},
+			rlimits:        map[string]string{"nofile": "512:1024", "core": "0"},
+			unveilDefaults: true,
+			exp:            &drivers.ExitResult{ExitCode: 0},
+			stdoutRe:       regexp.MustCompile(`^512\n1024\n0\n$`),
+		},
+		// lower the cpu priority of the task
+		{
+			name:           "nice",
+			user:           "nomad-80000",
+			command:        "nice",
+			nice:           10,
+			unveilDefaults: true,
+			exp:            &drivers.ExitResult{ExitCode: 0},
+			stdoutRe:       regexp.MustCompile(`^10\n$`),
+		},
+		// mount a /proc with only the process directories
+		{
+			name:           "proc subset pid",
+			user:           "nomad-80000",
+			command:        "sh",
+			args:           []string{"-c", "test -e /proc/self/status && test ! -e /proc/meminfo && echo hidden"},
+			proc:           "pid",
+			unveilDefaults: true,
+			exp:            &drivers.ExitResult{ExitCode: 0},
+			stdoutRe:       regexp.MustCompile(`hidden`),
+		},
 		{
 			name:           "pid namespace",
 			user:           "root",
@@ -427,6 +476,40 @@ func TestFunctional_cases(t *testing.T) {
 			exp:            &drivers.ExitResult{ExitCode: 0},
 			stdoutRe:       regexp.MustCompile(`\w+/tmp/tmp\.\w+`),
 		},
+		// use resolv.conf generated from the job dns config
+		{
+			name:           "dns config",
+			user:           "nomad-83000",
+			command:        "cat",
+			args:           []string{"/etc/resolv.conf"},
+			dns:            &drivers.DNSConfig{Servers: []string{"192.0.2.53"}, Searches: []string{"example.com"}},
+			unveilDefaults: true,
+			exp:            &drivers.ExitResult{ExitCode: 0},
+			stdoutRe:       regexp.MustCompile(`nameserver 192\.0\.2\.53`),
+		},
+		// use a uts namespace with a custom hostname
+		{
+			name:           "hostname",
+			user:           "nomad-84000",
+			command:        "cat",
+			args:           []string{"/proc/sys/kernel/hostname", "/etc/hostname"},
+			hostname:       "example-task",
+			unveilDefaults: true,
+			unveilPaths:    []string{"r:/proc/sys/kernel/hostname"},
+			exp:            &drivers.ExitResult{ExitCode: 0},
+			stdoutRe:       regexp.MustCompile(`^example-task\nexample-task$`),
+		},
+		// map the task user to root in a user namespace
+		{
+			name:           "user namespace",
+			user:           "nomad-85000",
+			command:        "id",
+			userns:         true,
+			allowUserns:    true,
+			unveilDefaults: true,
+			exp:            &drivers.ExitResult{ExitCode: 0},
+			stdoutRe:       regexp.MustCompile(`uid=0\(root\) gid=0\(root\) groups=0\(root\)`),
+		},
 	}
 
 	for _, tc := range cases {
@@ -435,12 +518,21 @@ func TestFunctional_cases(t *testing.T) {
 				UnveilDefaults: tc.unveilDefaults,
 				UnveilByTask:   tc.unveilByTask,
 				UnveilPaths:    tc.unveilPaths,
+
+				AllowUserNamespace: tc.allowUserns,
+				RlimitsByTask:      true,
 			}
 
 			taskConfig := &TaskConfig{
-				Command: tc.command,
-				Args:    tc.args,
-				Unveil:  tc.unveil,
+				Command:  tc.command,
+				Args:     tc.args,
+				Unveil:   tc.unveil,
+				Hostname: tc.hostname,
+
+				UserNamespace: tc.userns,
+				Proc:          tc.proc,
+				Rlimits:       tc.rlimits,
+				Nice:          tc.nice,
 			}
 
 			allocID := uuid.Generate()
@@ -452,6 +544,7 @@ func TestFunctional_cases(t *testing.T) {
 				Name:      taskName,
 				AllocID:   allocID,
 				Resources: basicResources(allocID, taskName),
+				DNS:       tc.dns,
 			}
 
 			must.NoError(t, task.EncodeConcreteDriverConfig(&taskConfig))
@@ -463,6 +556,10 @@ func TestFunctional_cases(t *testing.T) {
 
 			// Start the task
 			_, _, err := harness.StartTask(task)
+			if tc.startErr != "" {
+				must.ErrorContains(t, err, tc.startErr)
+				return
+			}
 			must.NoError(t, err)
 
 			defer func() { _ = harness.DestroyTask(task.ID, true) }()
@@ -526,6 +623,9 @@ func getLogs(t *testing.T, task *drivers.TaskConfig) (string, string) {
 
 func Test_doFingerprint_normal(t *testing.T) {
 	ctests.RequireRoot(t)
+	withSwapAccounting(t, true)
+	withCPUBurst(t, true)
+	withLandlockABI(t, 6, nil)
 
 	p := new(Plugin)
 	p.config = &Config{
@@ -537,11 +637,91 @@ func Test_doFingerprint_normal(t *testing.T) {
 	must.Eq(t, drivers.HealthStateHealthy, fp.Health)
 	must.Eq(t, drivers.DriverHealthy, fp.HealthDescription)
 	must.Eq(t, map[string]*dstructs.Attribute{
-		"driver.exec2.unveil.tasks":    dstructs.NewBoolAttribute(true),
-		"driver.exec2.unveil.defaults": dstructs.NewBoolAttribute(true),
+		"driver.exec2.unveil.tasks":         dstructs.NewBoolAttribute(true),
+		"driver.exec2.unveil.defaults":      dstructs.NewBoolAttribute(true),
+		"driver.exec2.user_namespace":       dstructs.NewBoolAttribute(false),
+		"driver.exec2.swap_accounting":      dstructs.NewBoolAttribute(true),
+		"driver.exec2.cpu_burst":            dstructs.NewBoolAttribute(true),
+		"driver.exec2.landlock.abi":         dstructs.NewIntAttribute(6, ""),
+		"driver.exec2.landlock.best_effort": dstructs.NewBoolAttribute(false),
 	}, fp.Attributes)
 }
 
+func Test_doFingerprint_landlock(t *testing.T) {
+	ctests.RequireRoot(t)
+
+	t.Run("missing", func(t *testing.T) {
+		withLandlockABI(t, 0, syscall.EOPNOTSUPP)
+		p := &Plugin{config: &Config{}}
+		fp := p.doFingerprint(exec.LookPath)
+		must.Eq(t, drivers.HealthStateUndetected, fp.Health)
+		must.Eq(t, "landlock is not available", fp.HealthDescription)
+	})
+
+	t.Run("mandatory", func(t *testing.T) {
+		withLandlockABI(t, 2, nil)
+		p := &Plugin{config: &Config{Landlock: landlockMandatory}}
+		fp := p.doFingerprint(exec.LookPath)
+		must.Eq(t, drivers.HealthStateUnhealthy, fp.Health)
+		must.Eq(t, "landlock ABI 2 cannot restrict {truncate}", fp.HealthDescription)
+	})
+
+	t.Run("mandatory abi 1", func(t *testing.T) {
+		withLandlockABI(t, 1, nil)
+		p := &Plugin{config: &Config{Landlock: landlockMandatory}}
+		fp := p.doFingerprint(exec.LookPath)
+		must.Eq(t, drivers.HealthStateUnhealthy, fp.Health)
+		must.Eq(t, "landlock ABI 1 cannot restrict {truncate}", fp.HealthDescription)
+	})
+
+	t.Run("mandatory supported", func(t *testing.T) {
+		withSwapAccounting(t, true)
+		withCPUBurst(t, true)
+		withLandlockABI(t, 3, nil)
+		p := &Plugin{config: &Config{Landlock: landlockMandatory}}
+		fp := p.doFingerprint(exec.LookPath)
+		must.Eq(t, drivers.HealthStateHealthy, fp.Health)
+		must.Eq(t, dstructs.NewIntAttribute(3, ""), fp.Attributes["driver.exec2.landlock.abi"])
+		must.Eq(t, dstructs.NewBoolAttribute(false), fp.Attributes["driver.exec2.landlock.best_effort"])
+	})
+
+	t.Run("best-effort", func(t *testing.T) {
+		withSwapAccounting(t, true)
+		withCPUBurst(t, true)
+		withLandlockABI(t, 2, nil)
+		p := &Plugin{config: &Config{Landlock: landlockBestEffort}}
+		fp := p.doFingerprint(exec.LookPath)
+		must.Eq(t, drivers.HealthStateHealthy, fp.Health)
+		must.Eq(t, dstructs.NewBoolAttribute(true), fp.Attributes["driver.exec2.landlock.best_effort"])
+	})
+}
+
+func Test_doFingerprint_userNamespace(t *testing.T) {
+	ctests.RequireRoot(t)
+	withLandlockABI(t, 6, nil)
+
+	cases := []struct {
+		name      string
+		allowed   bool
+		supported bool
+		exp       bool
+	}{
+		{name: "not allowed", allowed: false, supported: true, exp: false},
+		{name: "not supported", allowed: true, supported: false, exp: false},
+		{name: "allowed and supported", allowed: true, supported: true, exp: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			withUserNamespaces(t, tc.supported)
+			p := &Plugin{config: &Config{AllowUserNamespace: tc.allowed}}
+			fp := p.doFingerprint(exec.LookPath)
+			must.Eq(t, drivers.HealthStateHealthy, fp.Health)
+			must.Eq(t, dstructs.NewBoolAttribute(tc.exp), fp.Attributes["driver.exec2.user_namespace"])
+		})
+	}
+}
+
 func Test_doFingerprint_notRoot(t *testing.T) {
 	ctests.RequireNonRoot(t)
 
@@ -586,6 +766,908 @@ func Test_doFingerprint_missing_unshare(t *testing.T) {
 	must.Eq(t, "unshare executable not found", fp.HealthDescription)
 }
 
+func Test_setOptions_userNamespace(t *testing.T) {
+	task := &drivers.TaskConfig{
+		ID:      "a/b/c",
+		Name:    "b",
+		AllocID: uuid.Generate(),
+	}
+	must.NoError(t, task.EncodeConcreteDriverConfig(&TaskConfig{
+		Command:       "id",
+		UserNamespace: true,
+	}))
+
+	t.Run("not allowed", func(t *testing.T) {
+		p := &Plugin{config: &Config{}}
+		_, err := p.setOptions(task)
+		must.EqError(t, err, "task set user_namespace but driver config does not allow this")
+	})
+
+	t.Run("allowed", func(t *testing.T) {
+		p := &Plugin{config: &Config{AllowUserNamespace: true}}
+		opts, err := p.setOptions(task)
+		must.NoError(t, err)
+		must.True(t, opts.UserNamespace)
+	})
+}
+
+func Test_setOptions_landlock(t *testing.T) {
+	task := &drivers.TaskConfig{
+		ID:      "a/b/c",
+		Name:    "b",
+		AllocID: uuid.Generate(),
+	}
+	must.NoError(t, task.EncodeConcreteDriverConfig(&TaskConfig{
+		Command: "id",
+	}))
+
+	for _, landlock := range []string{landlockMandatory, landlockBestEffort} {
+		p := &Plugin{config: &Config{Landlock: landlock}}
+		opts, err := p.setOptions(task)
+		must.NoError(t, err)
+		must.Eq(t, landlock == landlockBestEffort, opts.BestEffort)
+	}
+}
+
+func Test_driverConfigSpec_profile(t *testing.T) {
+	var config Config
+	hclutils.NewConfigParser(driverConfigSpec).ParseHCL(t, `
+config {
+  profile "java" {
+    unveil = ["rx:/usr/lib/jvm", "r:/etc/java-17-openjdk"]
+  }
+  profile "python" {
+    unveil = ["rx:/usr/lib/python3"]
+  }
+}`, &config)
+
+	must.Eq(t, map[string]*Profile{
+		"java":   {Unveil: []string{"rx:/usr/lib/jvm", "r:/etc/java-17-openjdk"}},
+		"python": {Unveil: []string{"rx:/usr/lib/python3"}},
+	}, config.Profiles)
+}
+
+func Test_setOptions_profiles(t *testing.T) {
+	p := &Plugin{config: &Config{
+		UnveilPaths: []string{"r:/etc/ssl"},
+		Profiles: map[string]*Profile{
+			"java":   {Unveil: []string{"rx:/usr/lib/jvm"}},
+			"python": {Unveil: []string{"rx:/usr/lib/python3", "r:/etc/python3"}},
+		},
+	}}
+
+	cases := []struct {
+		name     string
+		profiles []string
+		unveil   []string
+		exp      []string
+		expErr   string
+	}{
+		{name: "none", exp: []string{"r:/etc/ssl"}},
+		{name: "java", profiles: []string{"java"}, exp: []string{"r:/etc/ssl", "rx:/usr/lib/jvm"}},
+		{
+			name:     "java and python",
+			profiles: []string{"java", "python"},
+			exp:      []string{"r:/etc/ssl", "rx:/usr/lib/jvm", "rx:/usr/lib/python3", "r:/etc/python3"},
+		},
+		{
+			name:     "unknown",
+			profiles: []string{"ruby"},
+			expErr:   `task set unveil profile "ruby" which is not defined in driver config`,
+		},
+		{
+			name:     "task unveil not allowed",
+			profiles: []string{"java"},
+			unveil:   []string{"r:/etc"},
+			expErr:   "task set unveil paths but driver config does not allow this",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			task := &drivers.TaskConfig{ID: "a/b/c"}
+			must.NoError(t, task.EncodeConcreteDriverConfig(&TaskConfig{
+				Command:  "cat",
+				Profiles: tc.profiles,
+				Unveil:   tc.unveil,
+			}))
+
+			opts, err := p.setOptions(task)
+			if tc.expErr != "" {
+				must.EqError(t, err, tc.expErr)
+				return
+			}
+			must.NoError(t, err)
+			must.Eq(t, tc.exp, opts.UnveilPaths)
+		})
+	}
+}
+
+func Test_setOptions_proc(t *testing.T) {
+	cases := []struct {
+		plugin string
+		task   string
+		exp    string
+		expErr string
+	}{
+		{plugin: "default", task: "", exp: ""},
+		{plugin: "default", task: "hardened", exp: "hardened"},
+		{plugin: "default", task: "pid", exp: "pid"},
+		{plugin: "hardened", task: "default", exp: "hardened"},
+		{plugin: "hardened", task: "pid", exp: "pid"},
+		{plugin: "pid", task: "hardened", exp: "pid"},
+		{plugin: "default", task: "bogus", expErr: `proc must be one of "default", "hardened", or "pid", got "bogus"`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.plugin+"_"+tc.task, func(t *testing.T) {
+			p := &Plugin{config: &Config{Proc: tc.plugin}}
+			task := &drivers.TaskConfig{ID: "a/b/c"}
+			must.NoError(t, task.EncodeConcreteDriverConfig(&TaskConfig{
+				Command: "cat",
+				Proc:    tc.task,
+			}))
+
+			opts, err := p.setOptions(task)
+			if tc.expErr != "" {
+				must.EqError(t, err, tc.expErr)
+				return
+			}
+			must.NoError(t, err)
+			must.Eq(t, tc.exp, opts.Proc)
+		})
+	}
+}
+
+func Test_setOptions_rlimits(t *testing.T) {
+	withInheritedRlimits(t, map[string]string{"nproc": "8192:unlimited", "stack": "8388608:unlimited"})
+
+	p := &Plugin{config: &Config{
+		Rlimits:       map[string]string{"nofile": "1024:4096", "core": "0"},
+		RlimitsMax:    map[string]string{"nofile": "65536", "nproc": "4096", "stack": "unlimited"},
+		RlimitsByTask: true,
+	}}
+
+	cases := []struct {
+		name   string
+		task   map[string]string
+		exp    []string
+		expErr string
+	}{
+		{
+			name: "defaults",
+			exp:  []string{"core=0:0", "nofile=1024:4096", "nproc=4096:4096"},
+		},
+		{
+			name: "override",
+			task: map[string]string{"nofile": "65536", "cpu": "60:120"},
+			exp:  []string{"core=0:0", "cpu=60:120", "nofile=65536:65536", "nproc=4096:4096"},
+		},
+		{
+			name: "override inherited",
+			task: map[string]string{"nproc": "100:200"},
+			exp:  []string{"core=0:0", "nofile=1024:4096", "nproc=100:200"},
+		},
+		{
+			name:   "exceeds maximum",
+			task:   map[string]string{"nofile": "1024:100000"},
+			expErr: "rlimit nofile of 1024:100000 exceeds maximum of 65536",
+		},
+		{
+			name:   "unlimited exceeds maximum",
+			task:   map[string]string{"nofile": "unlimited"},
+			expErr: "rlimit nofile of unlimited:unlimited exceeds maximum of 65536",
+		},
+		{
+			name:   "invalid",
+			task:   map[string]string{"files": "10"},
+			expErr: `rlimit "files" is not a known resource limit`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			task := &drivers.TaskConfig{ID: "a/b/c"}
+			must.NoError(t, task.EncodeConcreteDriverConfig(&TaskConfig{
+				Command: "cat",
+				Rlimits: tc.task,
+			}))
+
+			opts, err := p.setOptions(task)
+			if tc.expErr != "" {
+				must.EqError(t, err, tc.expErr)
+				return
+			}
+			must.NoError(t, err)
+
+			result := make([]string, 0, len(opts.Rlimits))
+			for _, limit := range opts.Rlimits {
+				result = append(result, limit.Name+"="+limit.String())
+			}
+			must.Eq(t, tc.exp, result)
+		})
+	}
+}
+
+// withInheritedRlimits overrides the resource limits inherited from the Nomad
+// agent for the duration of the test.
+func withInheritedRlimits(t *testing.T, limits map[string]string) {
+	original := inheritedRlimit
+	inheritedRlimit = func(name string) (shim.Rlimit, error) {
+		value, exists := limits[name]
+		if !exists {
+			value = "unlimited"
+		}
+		return shim.ParseRlimit(name, value)
+	}
+	t.Cleanup(func() { inheritedRlimit = original })
+}
+
+func Test_validateRlimits(t *testing.T) {
+	must.NoError(t, validateRlimits(&Config{
+		Rlimits:    map[string]string{"nofile": "1024:4096"},
+		RlimitsMax: map[string]string{"nofile": "4096"},
+	}))
+
+	err := validateRlimits(&Config{
+		Rlimits:    map[string]string{"nofile": "1024:8192"},
+		RlimitsMax: map[string]string{"nofile": "4096"},
+	})
+	must.EqError(t, err, "invalid rlimits: rlimit nofile of 1024:8192 exceeds maximum of 4096")
+
+	err = validateRlimits(&Config{
+		RlimitsMax: map[string]string{"nofile": "lots"},
+	})
+	must.EqError(t, err, `invalid rlimits_max: rlimit nofile has invalid soft limit: "lots" is not a number or "unlimited"`)
+}
+
+func Test_setOptions_sched(t *testing.T) {
+	p := &Plugin{config: &Config{NiceMin: -5, IOPriorityMin: 4, CPUWeightMax: 100}}
+
+	cases := []struct {
+		name   string
+		config *TaskConfig
+		exp    *shim.Sched
+		expErr string
+	}{
+		{
+			name:   "inherit",
+			config: &TaskConfig{},
+		},
+		{
+			name:   "batch",
+			config: &TaskConfig{Nice: 10, SchedPolicy: "batch"},
+			exp:    &shim.Sched{Nice: 10, Policy: "batch"},
+		},
+		{
+			name:   "io priority implies best effort",
+			config: &TaskConfig{IOPriority: pointer.Of(7)},
+			exp:    &shim.Sched{IOClass: "best-effort", IOPriority: 7},
+		},
+		{
+			name:   "idle",
+			config: &TaskConfig{SchedPolicy: "idle", IOClass: "idle"},
+			exp:    &shim.Sched{Policy: "idle", IOClass: "idle"},
+		},
+		{
+			name:   "nice below minimum",
+			config: &TaskConfig{Nice: -10},
+			expErr: "nice -10 is below minimum of -5 allowed by driver config",
+		},
+		{
+			name:   "nice out of range",
+			config: &TaskConfig{Nice: 20},
+			expErr: "nice must be between -20 and 19",
+		},
+		{
+			name:   "realtime policy",
+			config: &TaskConfig{SchedPolicy: "fifo"},
+			expErr: `sched_policy must be one of "other", "batch", or "idle", got "fifo"`,
+		},
+		{
+			name:   "realtime io class",
+			config: &TaskConfig{IOClass: "realtime"},
+			expErr: `io_class must be one of "best-effort" or "idle", got "realtime"`,
+		},
+		{
+			name:   "io priority with idle class",
+			config: &TaskConfig{IOClass: "idle", IOPriority: pointer.Of(7)},
+			expErr: `io_priority requires io_class "best-effort"`,
+		},
+		{
+			name:   "io priority below minimum",
+			config: &TaskConfig{IOPriority: pointer.Of(0)},
+			expErr: "io_priority 0 is below minimum of 4 allowed by driver config",
+		},
+		{
+			name:   "cpu weight above maximum",
+			config: &TaskConfig{CPUWeight: 200},
+			expErr: "cpu_weight 200 exceeds maximum of 100 allowed by driver config",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.config.Command = "cat"
+			task := &drivers.TaskConfig{ID: "a/b/c"}
+			must.NoError(t, task.EncodeConcreteDriverConfig(tc.config))
+
+			opts, err := p.setOptions(task)
+			if tc.expErr != "" {
+				must.EqError(t, err, tc.expErr)
+				return
+			}
+			must.NoError(t, err)
+			must.Eq(t, tc.exp, opts.Sched)
+		})
+	}
+}
+
+func Test_memoryLimits(t *testing.T) {
+	memory := func(memory, memoryMax int64) *drivers.Resources {
+		return &drivers.Resources{
+			NomadResources: &structs.AllocatedTaskResources{
+				Memory: structs.AllocatedMemoryResources{
+					MemoryMB:    memory,
+					MemoryMaxMB: memoryMax,
+				},
+			},
+		}
+	}
+
+	const mib = 1024 * 1024
+
+	cases := []struct {
+		name      string
+		config    *TaskConfig
+		resources *drivers.Resources
+		expHigh   uint64
+		expMin    uint64
+		expErr    string
+	}{
+		{
+			name:      "unset",
+			config:    &TaskConfig{},
+			resources: memory(256, 0),
+		},
+		{
+			name:      "high explicit",
+			config:    &TaskConfig{MemoryHigh: "200"},
+			resources: memory(256, 0),
+			expHigh:   200 * mib,
+		},
+		{
+			name:      "high percent of memory",
+			config:    &TaskConfig{MemoryHigh: "75%"},
+			resources: memory(256, 0),
+			expHigh:   192 * mib,
+		},
+		{
+			name:      "high percent of memory max",
+			config:    &TaskConfig{MemoryHigh: "50%", MemoryMin: 128},
+			resources: memory(256, 1024),
+			expHigh:   512 * mib,
+			expMin:    128 * mib,
+		},
+		{
+			name:      "high exceeds limit",
+			config:    &TaskConfig{MemoryHigh: "300"},
+			resources: memory(256, 0),
+			expErr:    "memory_high of 300 MiB exceeds memory limit of 256 MiB",
+		},
+		{
+			name:      "high invalid",
+			config:    &TaskConfig{MemoryHigh: "lots"},
+			resources: memory(256, 0),
+			expErr:    `memory_high must be a positive number of MiB or a percentage, got "lots"`,
+		},
+		{
+			name:      "high percent too large",
+			config:    &TaskConfig{MemoryHigh: "150%"},
+			resources: memory(256, 0),
+			expErr:    "memory_high must not exceed 100%",
+		},
+		{
+			name:      "min exceeds memory",
+			config:    &TaskConfig{MemoryMin: 512},
+			resources: memory(256, 1024),
+			expErr:    "memory_min of 512 MiB exceeds memory of 256 MiB",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			high, low, err := memoryLimits(tc.config, tc.resources)
+			if tc.expErr != "" {
+				must.EqError(t, err, tc.expErr)
+				return
+			}
+			must.NoError(t, err)
+			must.Eq(t, tc.expHigh, high)
+			must.Eq(t, tc.expMin, low)
+		})
+	}
+}
+
+func Test_memoryEventStats(t *testing.T) {
+	now := time.Now()
+	stats := memoryEventStats(resources.MemoryEvents{High: 12, OOMKill: 1}, now)
+	must.Eq(t, "cgroup", stats.Vendor)
+	must.Eq(t, "memory.events", stats.Type)
+	must.Eq(t, "memory_high", stats.Name)
+
+	instance := stats.InstanceStats["memory.events"]
+	must.Eq(t, int64(12), *instance.Summary.IntNumeratorVal)
+	must.Eq(t, int64(1), *instance.Stats.Attributes["oom_kill"].IntNumeratorVal)
+	must.Eq(t, now, instance.Timestamp)
+}
+
+// withSwapAccounting overrides whether the node has swap accounting for the
+// duration of the test.
+func withSwapAccounting(t *testing.T, enabled bool) {
+	original := swapAccounting
+	swapAccounting = func() bool { return enabled }
+	t.Cleanup(func() { swapAccounting = original })
+}
+
+func Test_setOptions_swap(t *testing.T) {
+	cases := []struct {
+		name       string
+		accounting bool
+		plugin     string
+		task       string
+		exp        string
+		expErr     string
+	}{
+		{name: "unlimited", accounting: true, plugin: "unlimited", exp: ""},
+		{name: "plugin default", accounting: true, plugin: "disabled", exp: "0"},
+		{name: "task size", accounting: true, plugin: "disabled", task: "256", exp: "268435456"},
+		{name: "task unlimited", accounting: true, plugin: "disabled", task: "unlimited", exp: ""},
+		{name: "no accounting unlimited", accounting: false, plugin: "unlimited", exp: ""},
+		{
+			name:       "no accounting disabled",
+			accounting: false,
+			plugin:     "unlimited",
+			task:       "disabled",
+			expErr:     `swap of "disabled" requires swap accounting, which is not enabled on this node`,
+		},
+		{
+			name:       "invalid",
+			accounting: true,
+			task:       "1G",
+			expErr:     `swap must be "disabled", "unlimited", or a number of MiB, got "1G"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			withSwapAccounting(t, tc.accounting)
+
+			p := &Plugin{config: &Config{Swap: tc.plugin}}
+			task := &drivers.TaskConfig{ID: "a/b/c"}
+			must.NoError(t, task.EncodeConcreteDriverConfig(&TaskConfig{
+				Command: "cat",
+				Swap:    tc.task,
+			}))
+
+			opts, err := p.setOptions(task)
+			if tc.expErr != "" {
+				must.EqError(t, err, tc.expErr)
+				return
+			}
+			must.NoError(t, err)
+			must.Eq(t, tc.exp, opts.SwapMax)
+		})
+	}
+}
+
+func withLandlockABI(t *testing.T, abi int, err error) {
+	original := landlockABI
+	landlockABI = func() (int, error) { return abi, err }
+	t.Cleanup(func() { landlockABI = original })
+}
+
+func withUserNamespaces(t *testing.T, supported bool) {
+	original := userNamespaces
+	userNamespaces = func(string, string) bool { return supported }
+	t.Cleanup(func() { userNamespaces = original })
+}
+
+func withCPUBurst(t *testing.T, supported bool) {
+	original := cpuBurst
+	cpuBurst = func() bool { return supported }
+	t.Cleanup(func() { cpuBurst = original })
+}
+
+// withSpecs overrides the cpu specs of the node for the duration of the test.
+func withSpecs(t *testing.T, compute cpustats.Compute) {
+	original := resources.GetSpecs()
+	resources.SetSpecs(compute)
+	t.Cleanup(func() {
+		resources.SetSpecs(cpustats.Compute{
+			TotalCompute: hw.MHz(original.Ticks()),
+			NumCores:     original.Cores,
+		})
+	})
+}
+
+func Test_cpuBurstLimits(t *testing.T) {
+	withSpecs(t, cpustats.Compute{TotalCompute: 8000, NumCores: 4})
+
+	cases := []struct {
+		name      string
+		supported bool
+		config    *TaskConfig
+		expPeriod uint64
+		expBurst  uint64
+		expErr    string
+	}{
+		{name: "default", supported: true, config: &TaskConfig{}, expPeriod: 100000},
+		{name: "period", supported: true, config: &TaskConfig{CPUPeriod: 50000}, expPeriod: 50000},
+		{name: "burst", supported: true, config: &TaskConfig{CPUBurst: 500}, expPeriod: 100000, expBurst: 25000},
+		{
+			name:      "burst and period",
+			supported: true,
+			config:    &TaskConfig{CPUPeriod: 20000, CPUBurst: 1000},
+			expPeriod: 20000,
+			expBurst:  10000,
+		},
+		{
+			name:      "period too short",
+			supported: true,
+			config:    &TaskConfig{CPUPeriod: 500},
+			expErr:    "cpu_period must be between 1000 and 1000000 microseconds",
+		},
+		{
+			name:      "period too short for cpu",
+			supported: true,
+			config:    &TaskConfig{CPUPeriod: 1000},
+			expErr:    "cpu_period of 1000 is too short for cpu of 1000 MHz",
+		},
+		{
+			name:      "burst exceeds cpu",
+			supported: true,
+			config:    &TaskConfig{CPUBurst: 2000},
+			expErr:    "cpu_burst of 2000 MHz exceeds cpu of 1000 MHz",
+		},
+		{
+			name:      "burst negative",
+			supported: true,
+			config:    &TaskConfig{CPUBurst: -1},
+			expErr:    "cpu_burst must not be negative",
+		},
+		{
+			name:      "burst not supported",
+			supported: false,
+			config:    &TaskConfig{CPUBurst: 500},
+			expErr:    "cpu_burst requires cpu.max.burst, which is not supported on this node",
+		},
+	}
+
+	r := &drivers.Resources{
+		NomadResources: &structs.AllocatedTaskResources{
+			Cpu: structs.AllocatedCpuResources{CpuShares: 1000},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			withCPUBurst(t, tc.supported)
+
+			period, burst, err := cpuBurstLimits(tc.config, r)
+			if tc.expErr != "" {
+				must.EqError(t, err, tc.expErr)
+				return
+			}
+			must.NoError(t, err)
+			must.Eq(t, tc.expPeriod, period)
+			must.Eq(t, tc.expBurst, burst)
+		})
+	}
+}
+
+func Test_numaMems(t *testing.T) {
+	original := memoryNodes
+	memoryNodes = func(cpus string) (string, error) {
+		must.Eq(t, "4-5", cpus)
+		return "1", nil
+	}
+	t.Cleanup(func() { memoryNodes = original })
+
+	reserved := &drivers.Resources{
+		NomadResources: &structs.AllocatedTaskResources{
+			Cpu: structs.AllocatedCpuResources{ReservedCores: []uint16{4, 5}},
+		},
+		LinuxResources: &drivers.LinuxResources{CpusetCpus: "4-5"},
+	}
+	shared := &drivers.Resources{
+		NomadResources: &structs.AllocatedTaskResources{
+			Cpu: structs.AllocatedCpuResources{CpuShares: 1000},
+		},
+		LinuxResources: &drivers.LinuxResources{CpusetCpus: "0-7"},
+	}
+
+	cases := []struct {
+		name      string
+		value     string
+		resources *drivers.Resources
+		exp       string
+		expErr    string
+	}{
+		{name: "default", resources: reserved, exp: ""},
+		{name: "open", value: "open", resources: reserved, exp: ""},
+		{name: "open shared", value: "open", resources: shared, exp: ""},
+		{name: "strict", value: "strict", resources: reserved, exp: "1"},
+		{
+			name:      "strict shared",
+			value:     "strict",
+			resources: shared,
+			expErr:    `numa of "strict" requires the task to reserve cores`,
+		},
+		{
+			name:      "invalid",
+			value:     "local",
+			resources: reserved,
+			expErr:    `numa must be "strict" or "open", got "local"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := numaMems(tc.value, tc.resources)
+			if tc.expErr != "" {
+				must.EqError(t, err, tc.expErr)
+				return
+			}
+			must.NoError(t, err)
+			must.Eq(t, tc.exp, result)
+		})
+	}
+}
+
+func Test_setOptions_tmpfs(t *testing.T) {
+	p := &Plugin{config: &Config{}}
+
+	cases := []struct {
+		name     string
+		allocDir string
+		config   *TaskConfig
+		expErr   string
+	}{
+		{
+			name:     "private tmp",
+			allocDir: "/opt/nomad/data/alloc/abc",
+			config:   &TaskConfig{Command: "env", TmpfsSize: 64},
+		},
+		{
+			name:     "negative size",
+			allocDir: "/opt/nomad/data/alloc/abc",
+			config:   &TaskConfig{Command: "env", TmpfsSize: -1},
+			expErr:   "tmpfs_size must not be negative",
+		},
+		{
+			name:     "tmpdir without size",
+			allocDir: "/opt/nomad/data/alloc/abc",
+			config:   &TaskConfig{Command: "env", TmpfsTmpDir: true},
+			expErr:   "tmpfs_tmpdir requires tmpfs_size to be set",
+		},
+		{
+			name:     "alloc dir under tmp",
+			allocDir: "/tmp/NomadClient123/abc",
+			config:   &TaskConfig{Command: "env", TmpfsSize: 64},
+			expErr:   "tmpfs_size cannot be used when the alloc directory is under /tmp",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			task := &drivers.TaskConfig{ID: "a/b/c", AllocDir: tc.allocDir}
+			must.NoError(t, task.EncodeConcreteDriverConfig(tc.config))
+
+			opts, err := p.setOptions(task)
+			if tc.expErr != "" {
+				must.EqError(t, err, tc.expErr)
+				return
+			}
+			must.NoError(t, err)
+			must.Eq(t, 64*1024*1024, opts.TmpfsSize)
+			must.SliceContains(t, opts.UnveilPaths, "rwc:/tmp")
+		})
+	}
+}
+
+func Test_users(t *testing.T) {
+	ctests.RequireRoot(t)
+
+	dir := t.TempDir()
+	config := &drivers.TaskConfig{
+		ID:       "a/b/c",
+		Name:     "task",
+		AllocDir: dir,
+		User:     "nomad-80000",
+		Env:      map[string]string{"NOMAD_TASK_DIR": filepath.Join(dir, "task", "local")},
+	}
+	must.NoError(t, os.MkdirAll(config.TaskDir().Dir, 0o755))
+
+	home := taskHome(config)
+	must.Eq(t, filepath.Join(dir, "task", "home"), home)
+
+	mounts, err := users(config)
+	must.NoError(t, err)
+	must.SliceLen(t, 4, mounts)
+	must.Eq(t, "/etc/passwd", mounts[0].Target)
+	must.Eq(t, "/etc/group", mounts[2].Target)
+
+	b, err := os.ReadFile(mounts[0].Source)
+	must.NoError(t, err)
+	must.StrContains(t, string(b), "nomad-80000:x:80000:80000:nomad workload user:"+home+":/bin/sh\n")
+
+	b, err = os.ReadFile(mounts[2].Source)
+	must.NoError(t, err)
+	must.StrContains(t, string(b), "nomad-80000:x:80000:\n")
+
+	info, err := os.Stat(home)
+	must.NoError(t, err)
+	must.True(t, info.IsDir())
+	must.Eq(t, uint32(80000), info.Sys().(*syscall.Stat_t).Uid)
+}
+
+func Test_users_notDynamic(t *testing.T) {
+	config := &drivers.TaskConfig{User: "nobody"}
+	must.Eq(t, "", taskHome(config))
+
+	mounts, err := users(config)
+	must.NoError(t, err)
+	must.SliceEmpty(t, mounts)
+}
+
+func Test_hostname(t *testing.T) {
+	cases := []struct {
+		task    string
+		allocID string
+		exp     string
+	}{
+		{
+			task:    "web",
+			allocID: "a1b2c3d4-e5f6-7890-abcd-ef0123456789",
+			exp:     "web-a1b2c3d4",
+		},
+		{
+			task:    "My_Task.1",
+			allocID: "a1b2c3d4-e5f6-7890-abcd-ef0123456789",
+			exp:     "my-task-1-a1b2c3d4",
+		},
+		{
+			task:    "___",
+			allocID: "a1b2c3d4-e5f6-7890-abcd-ef0123456789",
+			exp:     "a1b2c3d4",
+		},
+		{
+			task:    strings.Repeat("x", 100),
+			allocID: "a1b2c3d4-e5f6-7890-abcd-ef0123456789",
+			exp:     strings.Repeat("x", 54) + "-a1b2c3d4",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.task, func(t *testing.T) {
+			result := hostname(tc.task, tc.allocID)
+			must.Eq(t, tc.exp, result)
+			must.True(t, validHostname(result))
+		})
+	}
+}
+
+func Test_validHostname(t *testing.T) {
+	label := strings.Repeat("a", 63)
+
+	must.True(t, validHostname("web"))
+	must.True(t, validHostname("web-1.example.com"))
+	must.True(t, validHostname(label+"."+label))
+	must.True(t, validHostname(strings.Repeat(label+".", 3)+strings.Repeat("a", 61)))
+
+	must.False(t, validHostname(""))
+	must.False(t, validHostname("-web"))
+	must.False(t, validHostname("web."))
+	must.False(t, validHostname("web..example"))
+	must.False(t, validHostname("web_1.example"))
+	must.False(t, validHostname(label+"a.example"))
+	must.False(t, validHostname(strings.Repeat(label+".", 3)+strings.Repeat("a", 62)))
+}
+
+func Test_netnsPath(t *testing.T) {
+	cases := []struct {
+		name   string
+		config *drivers.TaskConfig
+		exp    string
+	}{
+		{
+			name:   "nil config",
+			config: nil,
+			exp:    "",
+		},
+		{
+			name:   "host",
+			config: &drivers.TaskConfig{ID: "a/b/c"},
+			exp:    "",
+		},
+		{
+			name: "group",
+			config: &drivers.TaskConfig{
+				ID: "a/b/c",
+				NetworkIsolation: &drivers.NetworkIsolationSpec{
+					Mode: drivers.NetIsolationModeGroup,
+					Path: "/var/run/netns/abc",
+				},
+			},
+			exp: "/var/run/netns/abc",
+		},
+		{
+			name: "task by isolation spec",
+			config: &drivers.TaskConfig{
+				ID: "a/b/c",
+				NetworkIsolation: &drivers.NetworkIsolationSpec{
+					Mode: drivers.NetIsolationModeTask,
+				},
+			},
+			exp: "/var/run/netns/exec2-a-b-c",
+		},
+		{
+			name: "task by driver network mode",
+			config: &drivers.TaskConfig{
+				ID: "a/b/c",
+				Resources: &drivers.Resources{
+					NomadResources: &structs.AllocatedTaskResources{
+						Networks: structs.Networks{{Mode: "driver"}},
+					},
+				},
+			},
+			exp: "/var/run/netns/exec2-a-b-c",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			must.Eq(t, tc.exp, netnsPath(tc.config))
+		})
+	}
+}
+
+func Test_Capabilities_initiateNetwork(t *testing.T) {
+	p := New(testlog.HCLogger(t)).(*Plugin)
+
+	caps, err := p.Capabilities()
+	must.NoError(t, err)
+	must.False(t, caps.MustInitiateNetwork)
+
+	p.config = &Config{InitiateNetwork: true}
+	caps, err = p.Capabilities()
+	must.NoError(t, err)
+	must.True(t, caps.MustInitiateNetwork)
+}
+
+func Test_CreateNetwork(t *testing.T) {
+	ctests.RequireRoot(t)
+
+	var p drivers.DriverNetworkManager = New(testlog.HCLogger(t)).(*Plugin)
+	allocID := uuid.Generate()
+
+	spec, created, err := p.CreateNetwork(allocID, nil)
+	must.NoError(t, err)
+	must.True(t, created)
+	must.Eq(t, drivers.NetIsolationModeGroup, spec.Mode)
+	must.Eq(t, "/var/run/netns/"+allocID, spec.Path)
+
+	// on recovery the existing namespace is kept
+	spec2, created, err := p.CreateNetwork(allocID, nil)
+	must.NoError(t, err)
+	must.False(t, created)
+	must.Eq(t, spec, spec2)
+
+	must.NoError(t, p.DestroyNetwork(allocID, spec))
+	must.FileNotExists(t, spec.Path)
+}
+
 func Test_tools(t *testing.T) {
 	t.Run("unshare", func(t *testing.T) {
 		path, err := exec.LookPath("unshare")
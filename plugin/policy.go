@@ -0,0 +1,171 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+
+	"github.com/hashicorp/nomad/plugins/drivers"
+)
+
+// Policy is a block of plugin config applying to the tasks of a matching Nomad
+// namespace and/or job. Settings left unset keep the value of the plugin
+// config, except unveil_paths which are added to those of the plugin config.
+type Policy struct {
+	Namespace string `codec:"namespace"`
+	Job       string `codec:"job"`
+
+	UnveilPaths       []string          `codec:"unveil_paths"`
+	UnveilByTask      *bool             `codec:"unveil_by_task"`
+	AutoUnveilCommand *bool             `codec:"auto_unveil_command"`
+	UnveilAllow       map[string]string `codec:"unveil_allow"`
+	UnveilDeny        []string          `codec:"unveil_deny"`
+
+	AllowUserNamespace *bool    `codec:"allow_user_namespace"`
+	Users              []string `codec:"users"`
+
+	RlimitsByTask *bool             `codec:"rlimits_by_task"`
+	RlimitsMax    map[string]string `codec:"rlimits_max"`
+
+	OOMScoreAdjMax *int `codec:"oom_score_adj_max"`
+	NiceMin        *int `codec:"nice_min"`
+	IOPriorityMin  *int `codec:"io_priority_min"`
+	CPUWeightMax   *int `codec:"cpu_weight_max"`
+}
+
+// matches reports whether the policy applies to the given task.
+func (p *Policy) matches(task *drivers.TaskConfig) bool {
+	if p.Namespace != "" {
+		if ok, _ := path.Match(p.Namespace, task.Namespace); !ok {
+			return false
+		}
+	}
+	if p.Job != "" {
+		if ok, _ := path.Match(p.Job, task.JobName); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// specificity ranks policies matching the same task; a policy matching the
+// job is more specific than one matching only the namespace.
+func (p *Policy) specificity() int {
+	var n int
+	if p.Namespace != "" {
+		n++
+	}
+	if p.Job != "" {
+		n += 2
+	}
+	return n
+}
+
+// validatePolicies checks the policy blocks of the plugin config.
+func validatePolicies(config *Config) error {
+	for name, policy := range config.Policies {
+		if policy.Namespace == "" && policy.Job == "" {
+			return fmt.Errorf("policy %q must set namespace or job", name)
+		}
+		for _, pattern := range append([]string{policy.Namespace, policy.Job}, policy.Users...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("policy %q has invalid pattern %q", name, pattern)
+			}
+		}
+		if err := validateRlimits(&Config{RlimitsMax: policy.RlimitsMax}); err != nil {
+			return fmt.Errorf("policy %q: %w", name, err)
+		}
+		if err := validateUnveilPolicy(&Config{UnveilAllow: policy.UnveilAllow, UnveilDeny: policy.UnveilDeny}); err != nil {
+			return fmt.Errorf("policy %q: %w", name, err)
+		}
+		if policy.OOMScoreAdjMax != nil {
+			if err := validateOOMScoreAdjMax(*policy.OOMScoreAdjMax); err != nil {
+				return fmt.Errorf("policy %q: %w", name, err)
+			}
+		}
+	}
+	for _, pattern := range config.Users {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("users has invalid pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// policy returns the plugin config with the most specific policy matching
+// the task applied, along with the name of that policy. Policies of equal
+// specificity are applied in the order of their names.
+func (p *Plugin) policy(task *drivers.TaskConfig) (*Config, string) {
+	var name string
+	for _, candidate := range slices.Sorted(maps.Keys(p.config.Policies)) {
+		policy := p.config.Policies[candidate]
+		if !policy.matches(task) {
+			continue
+		}
+		if name == "" || policy.specificity() > p.config.Policies[name].specificity() {
+			name = candidate
+		}
+	}
+	if name == "" {
+		return p.config, ""
+	}
+
+	policy := p.config.Policies[name]
+	config := *p.config
+	config.UnveilPaths = append(slices.Clone(config.UnveilPaths), policy.UnveilPaths...)
+	if policy.UnveilByTask != nil {
+		config.UnveilByTask = *policy.UnveilByTask
+	}
+	if policy.AutoUnveilCommand != nil {
+		config.AutoUnveilCommand = *policy.AutoUnveilCommand
+	}
+	if policy.UnveilAllow != nil {
+		config.UnveilAllow = policy.UnveilAllow
+	}
+	if policy.UnveilDeny != nil {
+		config.UnveilDeny = policy.UnveilDeny
+	}
+	if policy.AllowUserNamespace != nil {
+		config.AllowUserNamespace = *policy.AllowUserNamespace
+	}
+	if policy.Users != nil {
+		config.Users = policy.Users
+	}
+	if policy.RlimitsByTask != nil {
+		config.RlimitsByTask = *policy.RlimitsByTask
+	}
+	if policy.RlimitsMax != nil {
+		config.RlimitsMax = policy.RlimitsMax
+	}
+	if policy.OOMScoreAdjMax != nil {
+		config.OOMScoreAdjMax = *policy.OOMScoreAdjMax
+	}
+	if policy.NiceMin != nil {
+		config.NiceMin = *policy.NiceMin
+	}
+	if policy.IOPriorityMin != nil {
+		config.IOPriorityMin = *policy.IOPriorityMin
+	}
+	if policy.CPUWeightMax != nil {
+		config.CPUWeightMax = *policy.CPUWeightMax
+	}
+	return &config, name
+}
+
+// checkUser returns an error if the task user does not match any of the
+// users allowed by the config, if any.
+func checkUser(config *Config, user string) error {
+	if len(config.Users) == 0 {
+		return nil
+	}
+	for _, pattern := range config.Users {
+		if ok, _ := path.Match(pattern, user); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("task user %q is not allowed by driver config", user)
+}
@@ -0,0 +1,251 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/helper/pluginutils/hclutils"
+	"github.com/hashicorp/nomad/helper/pointer"
+	"github.com/hashicorp/nomad/plugins/drivers"
+	"github.com/shoenig/test/must"
+)
+
+func Test_driverConfigSpec_policy(t *testing.T) {
+	var config Config
+	hclutils.NewConfigParser(driverConfigSpec).ParseHCL(t, `
+config {
+  policy "batch" {
+    namespace         = "batch"
+    unveil_paths      = ["r:/srv/batch"]
+    unveil_by_task    = true
+    oom_score_adj_max = 500
+  }
+}`, &config)
+
+	must.Eq(t, map[string]*Policy{
+		"batch": {
+			Namespace:      "batch",
+			UnveilPaths:    []string{"r:/srv/batch"},
+			UnveilByTask:   pointer.Of(true),
+			OOMScoreAdjMax: pointer.Of(500),
+		},
+	}, config.Policies)
+
+	// settings not in the policy block keep the plugin config
+	must.True(t, config.RlimitsByTask)
+	must.Eq(t, 1000, config.OOMScoreAdjMax)
+}
+
+func Test_validatePolicies(t *testing.T) {
+	cases := []struct {
+		name   string
+		config *Config
+		expErr string
+	}{
+		{
+			name:   "valid",
+			config: &Config{Policies: map[string]*Policy{"a": {Namespace: "team-*"}}},
+		},
+		{
+			name:   "no match",
+			config: &Config{Policies: map[string]*Policy{"a": {}}},
+			expErr: `policy "a" must set namespace or job`,
+		},
+		{
+			name:   "bad pattern",
+			config: &Config{Policies: map[string]*Policy{"a": {Job: "web-["}}},
+			expErr: `policy "a" has invalid pattern "web-["`,
+		},
+		{
+			name: "bad rlimits_max",
+			config: &Config{Policies: map[string]*Policy{"a": {
+				Namespace:  "default",
+				RlimitsMax: map[string]string{"files": "10"},
+			}}},
+			expErr: `policy "a": invalid rlimits_max: rlimit "files" is not a known resource limit`,
+		},
+		{
+			name: "bad unveil_allow",
+			config: &Config{Policies: map[string]*Policy{"a": {
+				Namespace:   "default",
+				UnveilAllow: map[string]string{"/opt": "rz"},
+			}}},
+			expErr: `policy "a": unveil_allow of "/opt": mode "rz" has unknown right "rz"`,
+		},
+		{
+			name: "bad oom_score_adj_max",
+			config: &Config{Policies: map[string]*Policy{"a": {
+				Namespace:      "default",
+				OOMScoreAdjMax: pointer.Of(2000),
+			}}},
+			expErr: `policy "a": oom_score_adj_max must be between 0 and 1000, got 2000`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validatePolicies(tc.config)
+			if tc.expErr != "" {
+				must.EqError(t, err, tc.expErr)
+				return
+			}
+			must.NoError(t, err)
+		})
+	}
+}
+
+func TestPlugin_policy(t *testing.T) {
+	p := &Plugin{config: &Config{
+		UnveilPaths:    []string{"r:/etc/ssl"},
+		OOMScoreAdjMax: 1000,
+		IOPriorityMin:  4,
+		Policies: map[string]*Policy{
+			"team": {
+				Namespace:      "team-*",
+				UnveilPaths:    []string{"r:/srv/team"},
+				OOMScoreAdjMax: pointer.Of(100),
+			},
+			"team-etl": {
+				Namespace:    "team-a",
+				Job:          "etl-*",
+				UnveilByTask: pointer.Of(true),
+			},
+			"web": {
+				Job:           "web",
+				NiceMin:       pointer.Of(-5),
+				IOPriorityMin: pointer.Of(0),
+				Users:         []string{"nomad-*"},
+			},
+		},
+	}}
+
+	cases := []struct {
+		name      string
+		namespace string
+		job       string
+		exp       string
+	}{
+		{name: "none", namespace: "default", job: "api", exp: ""},
+		{name: "namespace", namespace: "team-b", job: "etl-daily", exp: "team"},
+		{name: "namespace and job", namespace: "team-a", job: "etl-daily", exp: "team-etl"},
+		{name: "job over namespace", namespace: "team-a", job: "web", exp: "web"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, name := p.policy(&drivers.TaskConfig{Namespace: tc.namespace, JobName: tc.job})
+			must.Eq(t, tc.exp, name)
+		})
+	}
+
+	config, _ := p.policy(&drivers.TaskConfig{Namespace: "team-b", JobName: "api"})
+	must.Eq(t, []string{"r:/etc/ssl", "r:/srv/team"}, config.UnveilPaths)
+	must.Eq(t, 100, config.OOMScoreAdjMax)
+	must.False(t, config.UnveilByTask)
+
+	config, _ = p.policy(&drivers.TaskConfig{Namespace: "team-a", JobName: "web"})
+	must.Eq(t, -5, config.NiceMin)
+	must.Eq(t, 0, config.IOPriorityMin)
+
+	// the plugin config itself is unchanged
+	must.Eq(t, []string{"r:/etc/ssl"}, p.config.UnveilPaths)
+	must.Eq(t, 1000, p.config.OOMScoreAdjMax)
+	must.Eq(t, 4, p.config.IOPriorityMin)
+}
+
+func Test_setOptions_policy(t *testing.T) {
+	p := &Plugin{config: &Config{
+		OOMScoreAdjMax: 1000,
+		RlimitsByTask:  true,
+		Policies: map[string]*Policy{
+			"restricted": {
+				Namespace:      "restricted",
+				Users:          []string{"nomad-*"},
+				RlimitsByTask:  pointer.Of(false),
+				OOMScoreAdjMax: pointer.Of(200),
+			},
+			"trusted": {
+				Namespace:    "trusted",
+				UnveilByTask: pointer.Of(true),
+			},
+		},
+	}}
+
+	cases := []struct {
+		name      string
+		namespace string
+		user      string
+		config    *TaskConfig
+		expErr    string
+	}{
+		{
+			name:      "default",
+			namespace: "default",
+			user:      "nobody",
+			config:    &TaskConfig{Command: "cat", OOMScoreAdj: 800, Rlimits: map[string]string{"core": "0"}},
+		},
+		{
+			name:      "restricted ok",
+			namespace: "restricted",
+			user:      "nomad-80000",
+			config:    &TaskConfig{Command: "cat", OOMScoreAdj: 200},
+		},
+		{
+			name:      "restricted negative oom_score_adj",
+			namespace: "restricted",
+			user:      "nomad-80000",
+			config:    &TaskConfig{Command: "cat", OOMScoreAdj: -100},
+			expErr:    "oom_score_adj must be between 0 and 1000",
+		},
+		{
+			name:      "restricted user",
+			namespace: "restricted",
+			user:      "nobody",
+			config:    &TaskConfig{Command: "cat"},
+			expErr:    `task user "nobody" is not allowed by driver config`,
+		},
+		{
+			name:      "restricted rlimits",
+			namespace: "restricted",
+			user:      "nomad-80000",
+			config:    &TaskConfig{Command: "cat", Rlimits: map[string]string{"core": "0"}},
+			expErr:    "task set rlimits but driver config does not allow this",
+		},
+		{
+			name:      "restricted oom_score_adj",
+			namespace: "restricted",
+			user:      "nomad-80000",
+			config:    &TaskConfig{Command: "cat", OOMScoreAdj: 800},
+			expErr:    "oom_score_adj 800 exceeds maximum of 200 allowed by driver config",
+		},
+		{
+			name:      "default unveil",
+			namespace: "default",
+			user:      "nobody",
+			config:    &TaskConfig{Command: "cat", Unveil: []string{"r:/srv"}},
+			expErr:    "task set unveil paths but driver config does not allow this",
+		},
+		{
+			name:      "trusted unveil",
+			namespace: "trusted",
+			user:      "nobody",
+			config:    &TaskConfig{Command: "cat", Unveil: []string{"r:/srv"}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			task := &drivers.TaskConfig{ID: "a/b/c", Namespace: tc.namespace, User: tc.user}
+			must.NoError(t, task.EncodeConcreteDriverConfig(tc.config))
+
+			_, err := p.setOptions(task)
+			if tc.expErr != "" {
+				must.EqError(t, err, tc.expErr)
+				return
+			}
+			must.NoError(t, err)
+		})
+	}
+}
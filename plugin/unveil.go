@@ -0,0 +1,470 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/nomad-driver-exec2/pkg/ldd"
+	"github.com/hashicorp/nomad-driver-exec2/pkg/shim"
+	"github.com/hashicorp/nomad/plugins/drivers"
+)
+
+// splitUnveil splits an unveil entry of the form "[?]<mode>:<path>" into its
+// mode and path, the same way the shim does before unveiling the path. The
+// optional marker is dropped.
+func splitUnveil(entry string) (string, string, error) {
+	entry = strings.TrimPrefix(entry, shim.Optional)
+	idx := strings.LastIndex(entry, ":")
+	if idx == -1 {
+		return "", "", fmt.Errorf("path %q does not contain mode prefix", entry)
+	}
+	return entry[:idx], entry[idx+1:], nil
+}
+
+// within reports whether path is prefix or a path under prefix.
+func within(path, prefix string) bool {
+	if prefix == "/" {
+		return true
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// overlaps reports whether any path matched by pattern is within path, or
+// contains path, comparing the components of both one by one.
+func overlaps(pattern, path string) bool {
+	if pattern == "/" || path == "/" {
+		return true
+	}
+	patterns := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	names := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i := range min(len(patterns), len(names)) {
+		if ok, _ := filepath.Match(patterns[i], names[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// literalPrefix returns the leading components of pattern which are not glob
+// patterns, which every path matched by pattern is within.
+func literalPrefix(pattern string) string {
+	prefix := "/"
+	for _, name := range strings.Split(strings.TrimPrefix(pattern, "/"), "/") {
+		if shim.IsGlob(name) {
+			break
+		}
+		prefix = filepath.Join(prefix, name)
+	}
+	return prefix
+}
+
+// validateUnveilPolicy checks the unveil_allow and unveil_deny settings of the
+// plugin config.
+func validateUnveilPolicy(config *Config) error {
+	for prefix, mode := range config.UnveilAllow {
+		if !filepath.IsAbs(prefix) {
+			return fmt.Errorf("unveil_allow prefix %q must be an absolute path", prefix)
+		}
+		if _, err := shim.ParseMode(mode); err != nil {
+			return fmt.Errorf("unveil_allow of %q: %w", prefix, err)
+		}
+	}
+	for _, path := range config.UnveilDeny {
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("unveil_deny path %q must be an absolute path", path)
+		}
+	}
+	return nil
+}
+
+// checkUnveil checks the unveil entries set by a task against the unveil
+// policy of the plugin config. An entry must not unveil a path in or above a
+// denied path, and if any prefixes are allowed the entry must be under one of
+// them, with a mode within the maximum mode of the most specific one.
+func checkUnveil(config *Config, entries []string) error {
+	for _, entry := range entries {
+		if err := checkUnveilEntry(config, entry); err != nil {
+			return fmt.Errorf("task unveil entry %q is not allowed: %w", entry, err)
+		}
+	}
+	return nil
+}
+
+func checkUnveilEntry(config *Config, entry string) error {
+	// an exclusion only takes away access from other entries
+	if path, ok := strings.CutPrefix(entry, shim.Exclude); ok {
+		if _, err := filepath.Match(path, ""); err != nil || !filepath.IsAbs(path) {
+			return fmt.Errorf("path must be an absolute path or pattern")
+		}
+		return nil
+	}
+
+	mode, path, err := splitUnveil(entry)
+	if err != nil {
+		return err
+	}
+	access, err := shim.ParseMode(mode)
+	if err != nil {
+		return err
+	}
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("path must be absolute")
+	}
+	path = filepath.Clean(path)
+	if _, err = filepath.Match(path, ""); err != nil {
+		return fmt.Errorf("path is an invalid pattern")
+	}
+	if err = checkUnveilPath(config, path, access); err != nil {
+		return err
+	}
+
+	// landlock unveils the target of a symlink, so each path matched by the
+	// entry must be within the policy once resolved as well
+	for _, resolved := range resolveMatches(path) {
+		if err = checkUnveilPath(config, resolved, access); err != nil {
+			return fmt.Errorf("path resolves to %s: %w", resolved, err)
+		}
+	}
+	return nil
+}
+
+// checkUnveilPath checks a path or pattern unveiled with the given access
+// against the unveil policy of the plugin config.
+func checkUnveilPath(config *Config, path string, access shim.Access) error {
+	for _, denied := range config.UnveilDeny {
+		for _, denied := range policyPaths(denied) {
+			if overlaps(path, denied) {
+				return fmt.Errorf("path %s is denied by driver config", denied)
+			}
+		}
+	}
+
+	if len(config.UnveilAllow) == 0 {
+		return nil
+	}
+
+	// find the most specific allowed prefix containing the path
+	path = literalPrefix(path)
+	var prefix, maximum string
+	for allowed, allowedMode := range config.UnveilAllow {
+		for _, allowed := range policyPaths(allowed) {
+			if within(path, allowed) && len(allowed) > len(prefix) {
+				prefix, maximum = allowed, allowedMode
+			}
+		}
+	}
+	if prefix == "" {
+		return fmt.Errorf("path is not under a prefix allowed by driver config")
+	}
+	if allowed, _ := shim.ParseMode(maximum); access&^allowed != 0 {
+		return fmt.Errorf("mode exceeds %q allowed under %s by driver config", maximum, prefix)
+	}
+	return nil
+}
+
+// policyPaths returns a path of the unveil policy as written, and with
+// symlinks resolved if it exists, so that it matches paths in either form.
+func policyPaths(path string) []string {
+	path = filepath.Clean(path)
+	if resolved, err := filepath.EvalSymlinks(path); err == nil && resolved != path {
+		return []string{path, resolved}
+	}
+	return []string{path}
+}
+
+// resolveMatches returns the existing paths matched by path, which may be a
+// pattern, with symlinks resolved.
+func resolveMatches(path string) []string {
+	matches := []string{path}
+	if shim.IsGlob(path) {
+		matches, _ = filepath.Glob(path)
+	}
+	result := make([]string, 0, len(matches))
+	for _, match := range matches {
+		if resolved, err := filepath.EvalSymlinks(match); err == nil {
+			result = append(result, resolved)
+		}
+	}
+	return result
+}
+
+// UnveilError is the error of an unveil entry, or of the command of a task,
+// which cannot be used to start the task.
+type UnveilError struct {
+	Entry string // the unveil entry or command
+	Err   error
+}
+
+func (e *UnveilError) Error() string {
+	return fmt.Sprintf("unveil %q: %v", e.Entry, e.Err)
+}
+
+func (e *UnveilError) Unwrap() error {
+	return e.Err
+}
+
+// unveiled is an unveil entry with its path resolved.
+type unveiled struct {
+	mode     string
+	path     string
+	excluded bool // excluded from the other paths
+}
+
+// resolveUnveil checks each unveil entry has a valid mode and an absolute path
+// which exists, and returns the entries with symlinks in their path resolved,
+// which is what the shim unveils. Glob patterns are expanded to the paths they
+// match, and optional entries whose path does not exist are skipped.
+func resolveUnveil(entries []string) ([]unveiled, error) {
+	result := make([]unveiled, 0, len(entries))
+	for _, entry := range entries {
+		if path, ok := strings.CutPrefix(entry, shim.Exclude); ok {
+			excluded, err := resolveExclusion(path)
+			if err != nil {
+				return nil, &UnveilError{Entry: entry, Err: err}
+			}
+			result = append(result, excluded...)
+			continue
+		}
+
+		optional := strings.HasPrefix(entry, shim.Optional)
+		mode, path, err := splitUnveil(entry)
+		if err == nil {
+			_, err = shim.ParseMode(mode)
+		}
+		switch {
+		case err != nil:
+			return nil, &UnveilError{Entry: entry, Err: err}
+		case !filepath.IsAbs(path):
+			return nil, &UnveilError{Entry: entry, Err: fmt.Errorf("path %s must be absolute", path)}
+		}
+
+		matches := []string{path}
+		if shim.IsGlob(path) {
+			matches, err = filepath.Glob(path)
+			switch {
+			case err != nil:
+				return nil, &UnveilError{Entry: entry, Err: fmt.Errorf("path %s is an invalid pattern", path)}
+			case len(matches) == 0 && !optional:
+				return nil, &UnveilError{Entry: entry, Err: fmt.Errorf("pattern %s matches no paths", path)}
+			}
+		}
+
+		for _, match := range matches {
+			resolved, err := filepath.EvalSymlinks(match)
+			switch {
+			case err != nil && optional:
+				continue
+			case err != nil:
+				return nil, &UnveilError{Entry: entry, Err: fmt.Errorf("path %s does not exist or cannot be resolved", match)}
+			}
+			result = append(result, unveiled{mode: mode, path: resolved})
+		}
+	}
+	return result, nil
+}
+
+// resolveExclusion returns the paths matched by the path of an exclusion
+// entry with symlinks resolved. Paths which do not exist are not excluded.
+func resolveExclusion(path string) ([]unveiled, error) {
+	if !filepath.IsAbs(path) {
+		return nil, fmt.Errorf("path %s must be absolute", path)
+	}
+	matches, err := filepath.Glob(path)
+	if err != nil {
+		return nil, fmt.Errorf("path %s is an invalid pattern", path)
+	}
+	result := make([]unveiled, 0, len(matches))
+	for _, match := range matches {
+		if resolved, err := filepath.EvalSymlinks(match); err == nil {
+			result = append(result, unveiled{path: resolved, excluded: true})
+		}
+	}
+	return result, nil
+}
+
+// resolveCommand returns the path of the command of a task with symlinks
+// resolved, found the same way as the shim does: relative to the task
+// directory if it contains a slash, otherwise in the PATH of the task.
+func resolveCommand(command, taskDir string, env map[string]string) (string, error) {
+	var path string
+	switch {
+	case strings.Contains(command, "/"):
+		path = command
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(taskDir, path)
+		}
+	default:
+		for _, dir := range filepath.SplitList(env["PATH"]) {
+			candidate := filepath.Join(dir, command)
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() && info.Mode()&0o111 != 0 {
+				path = candidate
+				break
+			}
+		}
+		if path == "" {
+			return "", fmt.Errorf("command not found in PATH %q", env["PATH"])
+		}
+	}
+
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("path %s does not exist or cannot be resolved", path)
+	}
+	return resolved, nil
+}
+
+// checkCommand returns an error unless the resolved command is within a path
+// unveiled with execute permission, including the default bin directories,
+// and not within an excluded path.
+func checkCommand(command string, paths []unveiled, defaults bool) error {
+	for _, p := range paths {
+		if p.excluded && within(command, p.path) {
+			return fmt.Errorf("path %s is excluded by unveil path %s", command, p.path)
+		}
+	}
+	for _, p := range paths {
+		if p.excluded {
+			continue
+		}
+		access, _ := shim.ParseMode(p.mode)
+		if access&shim.AccessExecute != 0 && within(command, p.path) {
+			return nil
+		}
+	}
+	if defaults {
+		for _, dir := range shim.DefaultBinDirs {
+			if resolved, err := filepath.EvalSymlinks(dir); err == nil && within(command, resolved) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("path %s is not unveiled with execute permission", command)
+}
+
+// validateUnveil checks the unveil entries and command of a task can be used
+// by the shim, so that a mistake fails to start the task with an error rather
+// than the task exiting with an error in its logs.
+func validateUnveil(config *drivers.TaskConfig, opts *shim.Options) error {
+	paths, err := resolveUnveil(opts.UnveilPaths)
+	if err != nil {
+		return err
+	}
+
+	command, err := resolveCommand(opts.Command, config.TaskDir().Dir, config.Env)
+	if err != nil {
+		return &UnveilError{Entry: opts.Command, Err: err}
+	}
+	if err = checkCommand(command, paths, opts.UnveilDefaults); err != nil {
+		return &UnveilError{Entry: opts.Command, Err: err}
+	}
+	return nil
+}
+
+// maxInterpreters is the depth of nested script interpreters the kernel
+// follows, beyond which a command cannot be executed.
+const maxInterpreters = 4
+
+// commandUnveil returns the minimal unveil entries needed to execute the
+// command of a task: the command itself, the interpreters of a script, and the
+// ELF interpreter and shared libraries of each program executed along the way.
+func commandUnveil(command, taskDir string, env map[string]string) ([]string, error) {
+	path, err := resolveCommand(command, taskDir, env)
+	if err != nil {
+		return nil, err
+	}
+
+	cache, err := ldd.ReadCache(ldd.CacheFile)
+	if err != nil {
+		return nil, err
+	}
+
+	programs := []string{path}
+	for range maxInterpreters {
+		interp, arg, err := ldd.Shebang(path)
+		if err != nil {
+			return nil, err
+		}
+		if interp == "" {
+			break
+		}
+		if path, err = resolveCommand(interp, "/", env); err != nil {
+			return nil, fmt.Errorf("interpreter %s: %w", interp, err)
+		}
+		programs = append(programs, path)
+
+		// a script run through env executes the program named by its
+		// argument, found in the PATH of the task
+		if filepath.Base(interp) == "env" {
+			if program := envProgram(arg); program != "" {
+				if path, err = resolveCommand(program, "/", env); err != nil {
+					return nil, fmt.Errorf("interpreter %s: %w", program, err)
+				}
+				programs = append(programs, path)
+			}
+		}
+	}
+
+	entries := make([]string, 0, len(programs))
+	for _, program := range programs {
+		entries = append(entries, "rx:"+program)
+	}
+
+	// each program is executed in turn, so needs its own libraries
+	add := func(entry string) {
+		if !slices.Contains(entries, entry) {
+			entries = append(entries, entry)
+		}
+	}
+	for _, program := range programs {
+		needs, err := ldd.Resolve(program, cache)
+		switch {
+		case errors.Is(err, ldd.ErrNotELF):
+			continue
+		case err != nil:
+			return nil, err
+		}
+		if needs.Interpreter != "" {
+			add("rx:" + needs.Interpreter)
+		}
+		for _, lib := range needs.Libraries {
+			add("r:" + lib)
+		}
+	}
+	return entries, nil
+}
+
+// envProgram returns the program named by the argument of an env shebang
+// line, skipping any options and variable assignments.
+func envProgram(arg string) string {
+	for _, field := range strings.Fields(arg) {
+		if strings.HasPrefix(field, "-") || strings.Contains(field, "=") {
+			continue
+		}
+		return field
+	}
+	return ""
+}
+
+// maxUnveilExpansion is the number of paths unveiled for a task beyond which
+// the driver warns, as exclusions are expanded into every other path of the
+// directories containing them.
+const maxUnveilExpansion = 1000
+
+// unveilExpansion returns the number of paths the shim unveils for a task
+// whose unveil entries contain exclusions, or 0 if there are none.
+func unveilExpansion(opts *shim.Options) int {
+	if !slices.ContainsFunc(opts.UnveilPaths, func(entry string) bool {
+		return strings.HasPrefix(entry, shim.Exclude)
+	}) {
+		return 0
+	}
+	n, _ := shim.Expanded(opts.UnveilDefaults, opts.UnveilPaths)
+	return n
+}
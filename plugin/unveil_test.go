@@ -0,0 +1,481 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/nomad-driver-exec2/pkg/ldd"
+	"github.com/hashicorp/nomad-driver-exec2/pkg/shim"
+	"github.com/hashicorp/nomad/plugins/drivers"
+	"github.com/shoenig/test/must"
+)
+
+func Test_validateUnveilPolicy(t *testing.T) {
+	cases := []struct {
+		name   string
+		config *Config
+		expErr string
+	}{
+		{name: "empty", config: &Config{}},
+		{
+			name: "valid",
+			config: &Config{
+				UnveilAllow: map[string]string{"/opt/data": "r", "/opt/bin": "rx"},
+				UnveilDeny:  []string{"/opt/data/secret"},
+			},
+		},
+		{
+			name:   "relative prefix",
+			config: &Config{UnveilAllow: map[string]string{"opt/data": "r"}},
+			expErr: `unveil_allow prefix "opt/data" must be an absolute path`,
+		},
+		{
+			name:   "bad mode",
+			config: &Config{UnveilAllow: map[string]string{"/opt/data": "rz"}},
+			expErr: `unveil_allow of "/opt/data": mode "rz" has unknown right "rz"`,
+		},
+		{
+			name:   "relative deny",
+			config: &Config{UnveilDeny: []string{"secret"}},
+			expErr: `unveil_deny path "secret" must be an absolute path`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateUnveilPolicy(tc.config)
+			if tc.expErr != "" {
+				must.EqError(t, err, tc.expErr)
+				return
+			}
+			must.NoError(t, err)
+		})
+	}
+}
+
+func Test_checkUnveil(t *testing.T) {
+	policy := &Config{
+		UnveilAllow: map[string]string{
+			"/opt/data":     "r",
+			"/opt/data/tmp": "rwc",
+			"/opt/bin":      "rx",
+		},
+		UnveilDeny: []string{"/opt/data/secret", "/etc/shadow"},
+	}
+
+	cases := []struct {
+		name   string
+		config *Config
+		entry  string
+		expErr string
+	}{
+		{name: "no policy", config: &Config{}, entry: "rwxc:/"},
+		{name: "allowed read", config: policy, entry: "r:/opt/data/set1"},
+		{name: "allowed prefix itself", config: policy, entry: "rx:/opt/bin"},
+		{name: "more specific prefix", config: policy, entry: "rwc:/opt/data/tmp/scratch"},
+		{
+			name:   "mode exceeds",
+			config: policy,
+			entry:  "rw:/opt/data/set1",
+			expErr: `task unveil entry "rw:/opt/data/set1" is not allowed: mode exceeds "r" allowed under /opt/data by driver config`,
+		},
+		{
+			name:   "root",
+			config: policy,
+			entry:  "rwxc:/",
+			expErr: `task unveil entry "rwxc:/" is not allowed: path /opt/data/secret is denied by driver config`,
+		},
+		{
+			name:   "not under prefix",
+			config: policy,
+			entry:  "r:/opt/database",
+			expErr: `task unveil entry "r:/opt/database" is not allowed: path is not under a prefix allowed by driver config`,
+		},
+		{
+			name:   "denied",
+			config: policy,
+			entry:  "r:/opt/data/secret/key",
+			expErr: `task unveil entry "r:/opt/data/secret/key" is not allowed: path /opt/data/secret is denied by driver config`,
+		},
+		{
+			name:   "denied parent",
+			config: &Config{UnveilDeny: []string{"/etc/shadow"}},
+			entry:  "r:/etc",
+			expErr: `task unveil entry "r:/etc" is not allowed: path /etc/shadow is denied by driver config`,
+		},
+		{
+			name:   "escape with dots",
+			config: policy,
+			entry:  "r:/opt/data/../../usr",
+			expErr: `task unveil entry "r:/opt/data/../../usr" is not allowed: path is not under a prefix allowed by driver config`,
+		},
+		{name: "optional", config: policy, entry: "?r:/opt/data/set1"},
+		{name: "exclusion", config: policy, entry: "!/etc/ssl/private"},
+		{
+			name:   "relative exclusion",
+			config: policy,
+			entry:  "!etc/ssl/private",
+			expErr: `task unveil entry "!etc/ssl/private" is not allowed: path must be an absolute path or pattern`,
+		},
+		{name: "extended mode", config: policy, entry: "read-dir,write-file,make-reg:/opt/data/tmp/logs"},
+		{
+			name:   "extended mode exceeds",
+			config: policy,
+			entry:  "r,make-sock:/opt/data/set1",
+			expErr: `task unveil entry "r,make-sock:/opt/data/set1" is not allowed: mode exceeds "r" allowed under /opt/data by driver config`,
+		},
+		{name: "glob", config: policy, entry: "r:/opt/data/set*/part-[0-9]"},
+		{
+			name:   "glob matching denied",
+			config: policy,
+			entry:  "r:/opt/data/*/key",
+			expErr: `task unveil entry "r:/opt/data/*/key" is not allowed: path /opt/data/secret is denied by driver config`,
+		},
+		{
+			name:   "glob above denied",
+			config: &Config{UnveilDeny: []string{"/etc/shadow"}},
+			entry:  "r:/e*",
+			expErr: `task unveil entry "r:/e*" is not allowed: path /etc/shadow is denied by driver config`,
+		},
+		{
+			name:   "glob not under prefix",
+			config: policy,
+			entry:  "r:/opt/*/set1",
+			expErr: `task unveil entry "r:/opt/*/set1" is not allowed: path is not under a prefix allowed by driver config`,
+		},
+		{
+			name:   "bad glob",
+			config: &Config{},
+			entry:  "r:/opt/[data",
+			expErr: `task unveil entry "r:/opt/[data" is not allowed: path is an invalid pattern`,
+		},
+		{
+			name:   "relative",
+			config: policy,
+			entry:  "r:opt/data",
+			expErr: `task unveil entry "r:opt/data" is not allowed: path must be absolute`,
+		},
+		{
+			name:   "bad mode",
+			config: &Config{},
+			entry:  "rq:/opt/data",
+			expErr: `task unveil entry "rq:/opt/data" is not allowed: mode "rq" has unknown right "rq"`,
+		},
+		{
+			name:   "no mode",
+			config: &Config{},
+			entry:  "/opt/data",
+			expErr: `task unveil entry "/opt/data" is not allowed: path "/opt/data" does not contain mode prefix`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkUnveil(tc.config, []string{tc.entry})
+			if tc.expErr != "" {
+				must.EqError(t, err, tc.expErr)
+				return
+			}
+			must.NoError(t, err)
+		})
+	}
+}
+
+func Test_checkUnveil_symlinks(t *testing.T) {
+	dir := t.TempDir()
+	task := filepath.Join(dir, "task")
+	secret := filepath.Join(dir, "secret")
+	other := filepath.Join(dir, "other")
+	for _, d := range []string{task, secret, other} {
+		must.NoError(t, os.Mkdir(d, 0o755))
+	}
+
+	// symlinks written by the task into its own directory
+	must.NoError(t, os.Symlink(secret, filepath.Join(task, "a-secret")))
+	must.NoError(t, os.Symlink(other, filepath.Join(task, "b-other")))
+	must.NoError(t, os.Mkdir(filepath.Join(task, "c-data"), 0o755))
+
+	policy := &Config{
+		UnveilAllow: map[string]string{task: "rwc", secret: "r"},
+		UnveilDeny:  []string{secret},
+	}
+
+	cases := []struct {
+		name   string
+		entry  string
+		expErr string
+	}{
+		{name: "directory", entry: "r:" + filepath.Join(task, "c-data")},
+		{name: "missing", entry: "?r:" + filepath.Join(task, "missing")},
+		{
+			name:   "link into denied",
+			entry:  "r:" + filepath.Join(task, "a-secret"),
+			expErr: fmt.Sprintf("path resolves to %s: path %s is denied by driver config", secret, secret),
+		},
+		{
+			name:   "link outside allowed",
+			entry:  "r:" + filepath.Join(task, "b-other"),
+			expErr: fmt.Sprintf("path resolves to %s: path is not under a prefix allowed by driver config", other),
+		},
+		{
+			name:   "glob matching link into denied",
+			entry:  "r:" + filepath.Join(task, "*"),
+			expErr: fmt.Sprintf("path resolves to %s: path %s is denied by driver config", secret, secret),
+		},
+		{
+			name:   "glob matching link outside allowed",
+			entry:  "r:" + filepath.Join(task, "[bc]-*"),
+			expErr: fmt.Sprintf("path resolves to %s: path is not under a prefix allowed by driver config", other),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkUnveil(policy, []string{tc.entry})
+			if tc.expErr != "" {
+				must.EqError(t, err, fmt.Sprintf("task unveil entry %q is not allowed: %s", tc.entry, tc.expErr))
+				return
+			}
+			must.NoError(t, err)
+		})
+	}
+
+	// a policy path which is a symlink matches paths in either form
+	link := filepath.Join(dir, "task-link")
+	must.NoError(t, os.Symlink(task, link))
+	must.NoError(t, checkUnveil(&Config{UnveilAllow: map[string]string{link: "r"}}, []string{"r:" + filepath.Join(task, "c-data")}))
+	must.Error(t, checkUnveil(&Config{UnveilDeny: []string{link}}, []string{"r:" + filepath.Join(task, "c-data")}))
+}
+
+func Test_setOptions_unveilPolicy(t *testing.T) {
+	p := &Plugin{config: &Config{
+		UnveilByTask: true,
+		UnveilAllow:  map[string]string{"/opt/data": "r"},
+	}}
+
+	task := &drivers.TaskConfig{ID: "a/b/c"}
+	must.NoError(t, task.EncodeConcreteDriverConfig(&TaskConfig{
+		Command: "cat",
+		Unveil:  []string{"r:/opt/data", "rwxc:/"},
+	}))
+
+	_, err := p.setOptions(task)
+	must.EqError(t, err, `task unveil entry "rwxc:/" is not allowed: path is not under a prefix allowed by driver config`)
+}
+
+func Test_resolveUnveil(t *testing.T) {
+	dir := t.TempDir()
+	real := filepath.Join(dir, "real")
+	link := filepath.Join(dir, "link")
+	must.NoError(t, os.Mkdir(real, 0o755))
+	must.NoError(t, os.Symlink(real, link))
+
+	paths, err := resolveUnveil([]string{"r:" + real, "rx:" + link})
+	must.NoError(t, err)
+	must.Eq(t, []unveiled{{mode: "r", path: real}, {mode: "rx", path: real}}, paths)
+
+	paths, err = resolveUnveil([]string{"?r:" + dir + "/missing", "?r:" + dir + "/missing-*", "x:" + dir + "/*"})
+	must.NoError(t, err)
+	must.Eq(t, []unveiled{{mode: "x", path: real}, {mode: "x", path: real}}, paths)
+
+	paths, err = resolveUnveil([]string{"r:" + dir, "!" + link, "!" + dir + "/missing"})
+	must.NoError(t, err)
+	must.Eq(t, []unveiled{{mode: "r", path: dir}, {path: real, excluded: true}}, paths)
+
+	cases := []struct {
+		name   string
+		entry  string
+		expErr string
+	}{
+		{name: "no mode", entry: real, expErr: fmt.Sprintf("path %q does not contain mode prefix", real)},
+		{name: "bad mode", entry: "rz:" + real, expErr: `mode "rz" has unknown right "rz"`},
+		{name: "relative", entry: "r:opt/app", expErr: "path opt/app must be absolute"},
+		{name: "missing", entry: "r:/opt/app/missing", expErr: "path /opt/app/missing does not exist or cannot be resolved"},
+		{name: "no matches", entry: "r:/opt/app/missing-*", expErr: "pattern /opt/app/missing-* matches no paths"},
+		{name: "bad pattern", entry: "r:/opt/app/[a", expErr: "path /opt/app/[a is an invalid pattern"},
+		{name: "relative exclusion", entry: "!opt/app", expErr: "path opt/app must be absolute"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := resolveUnveil([]string{tc.entry})
+			var unveilErr *UnveilError
+			must.ErrorAs(t, err, &unveilErr)
+			must.Eq(t, tc.entry, unveilErr.Entry)
+			must.EqError(t, err, fmt.Sprintf("unveil %q: %s", tc.entry, tc.expErr))
+		})
+	}
+}
+
+func Test_resolveCommand(t *testing.T) {
+	taskDir := t.TempDir()
+	bin := filepath.Join(taskDir, "local", "bin")
+	must.NoError(t, os.MkdirAll(bin, 0o755))
+	app := filepath.Join(bin, "app")
+	must.NoError(t, os.WriteFile(app, []byte("#!/bin/sh\n"), 0o755))
+	must.NoError(t, os.WriteFile(filepath.Join(bin, "data"), []byte("data\n"), 0o644))
+	must.NoError(t, os.Symlink(app, filepath.Join(bin, "alias")))
+
+	env := map[string]string{"PATH": "/nonexistent:" + bin}
+
+	cases := []struct {
+		name    string
+		command string
+		exp     string
+		expErr  string
+	}{
+		{name: "absolute", command: app, exp: app},
+		{name: "relative", command: "local/bin/app", exp: app},
+		{name: "path", command: "app", exp: app},
+		{name: "symlink", command: "alias", exp: app},
+		{name: "not executable", command: "data", expErr: `command not found in PATH "/nonexistent:` + bin + `"`},
+		{name: "missing", command: "local/bin/missing", expErr: "path " + filepath.Join(bin, "missing") + " does not exist or cannot be resolved"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := resolveCommand(tc.command, taskDir, env)
+			if tc.expErr != "" {
+				must.EqError(t, err, tc.expErr)
+				return
+			}
+			must.NoError(t, err)
+			must.Eq(t, tc.exp, result)
+		})
+	}
+}
+
+func Test_checkCommand(t *testing.T) {
+	paths := []unveiled{
+		{mode: "r", path: "/opt/data"},
+		{mode: "rx", path: "/opt/app"},
+	}
+
+	must.NoError(t, checkCommand("/opt/app/bin/server", paths, false))
+	must.NoError(t, checkCommand("/opt/tool", []unveiled{{mode: "read-file,execute", path: "/opt/tool"}}, false))
+	excluded := append(paths, unveiled{path: "/opt/app/bin", excluded: true})
+	must.EqError(t, checkCommand("/opt/app/bin/server", excluded, false), "path /opt/app/bin/server is excluded by unveil path /opt/app/bin")
+	must.EqError(t, checkCommand("/opt/data/run.sh", paths, false), "path /opt/data/run.sh is not unveiled with execute permission")
+	must.EqError(t, checkCommand("/opt/application", paths, false), "path /opt/application is not unveiled with execute permission")
+
+	cat, err := filepath.EvalSymlinks("/bin/cat")
+	must.NoError(t, err)
+	must.NoError(t, checkCommand(cat, nil, true))
+	must.EqError(t, checkCommand(cat, nil, false), "path "+cat+" is not unveiled with execute permission")
+}
+
+// needsEntries returns the unveil entries of the ELF interpreters and shared
+// libraries of the given programs, without duplicates.
+func needsEntries(t *testing.T, programs ...string) []string {
+	cache, err := ldd.ReadCache(ldd.CacheFile)
+	must.NoError(t, err)
+
+	var entries []string
+	for _, program := range programs {
+		needs, err := ldd.Resolve(program, cache)
+		must.NoError(t, err)
+		for _, entry := range append([]string{"rx:" + needs.Interpreter}, prefixed("r:", needs.Libraries)...) {
+			if entry != "rx:" && !slices.Contains(entries, entry) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries
+}
+
+func prefixed(prefix string, paths []string) []string {
+	result := make([]string, 0, len(paths))
+	for _, path := range paths {
+		result = append(result, prefix+path)
+	}
+	return result
+}
+
+func Test_commandUnveil(t *testing.T) {
+	sh, err := filepath.EvalSymlinks("/bin/sh")
+	must.NoError(t, err)
+
+	taskDir := t.TempDir()
+	script := filepath.Join(taskDir, "script.sh")
+	must.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho hi\n"), 0o755))
+	envScript := filepath.Join(taskDir, "env.sh")
+	must.NoError(t, os.WriteFile(envScript, []byte("#!/usr/bin/env -S LC_ALL=C sh -e\necho hi\n"), 0o755))
+	text := filepath.Join(taskDir, "text")
+	must.NoError(t, os.WriteFile(text, []byte("echo hi\n"), 0o755))
+	env, err := filepath.EvalSymlinks("/usr/bin/env")
+	must.NoError(t, err)
+	nested := filepath.Join(taskDir, "nested")
+	must.NoError(t, os.WriteFile(nested, []byte("#!"+envScript+"\necho hi\n"), 0o755))
+
+	path := map[string]string{"PATH": "/usr/bin:/bin"}
+
+	cases := []struct {
+		name    string
+		command string
+		exp     []string
+		expErr  string
+	}{
+		{name: "program", command: "sh", exp: append([]string{"rx:" + sh}, needsEntries(t, sh)...)},
+		{name: "script", command: script, exp: append([]string{"rx:" + script, "rx:" + sh}, needsEntries(t, sh)...)},
+		{
+			name:    "env script",
+			command: "./env.sh",
+			exp:     append([]string{"rx:" + envScript, "rx:" + env, "rx:" + sh}, needsEntries(t, env, sh)...),
+		},
+		{
+			name:    "nested script",
+			command: nested,
+			exp:     append([]string{"rx:" + nested, "rx:" + envScript, "rx:" + env, "rx:" + sh}, needsEntries(t, env, sh)...),
+		},
+		{name: "not elf", command: text, exp: []string{"rx:" + text}},
+		{name: "missing", command: "missing", expErr: `command not found in PATH "/usr/bin:/bin"`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := commandUnveil(tc.command, taskDir, path)
+			if tc.expErr != "" {
+				must.EqError(t, err, tc.expErr)
+				return
+			}
+			must.NoError(t, err)
+			must.Eq(t, tc.exp, result)
+		})
+	}
+}
+
+func Test_envProgram(t *testing.T) {
+	must.Eq(t, "python3", envProgram("python3"))
+	must.Eq(t, "sh", envProgram("-S LC_ALL=C sh -e"))
+	must.Eq(t, "", envProgram("-i"))
+}
+
+func Test_setOptions_autoUnveilCommand(t *testing.T) {
+	sh, err := filepath.EvalSymlinks("/bin/sh")
+	must.NoError(t, err)
+
+	task := &drivers.TaskConfig{ID: "a/b/c", Env: map[string]string{"PATH": "/usr/bin:/bin"}}
+	must.NoError(t, task.EncodeConcreteDriverConfig(&TaskConfig{Command: "sh"}))
+
+	p := &Plugin{config: &Config{AutoUnveilCommand: true}}
+	opts, err := p.setOptions(task)
+	must.NoError(t, err)
+	must.SliceContains(t, opts.UnveilPaths, "rx:"+sh)
+
+	p = &Plugin{config: &Config{AutoUnveilCommand: true, UnveilDeny: []string{sh}}}
+	_, err = p.setOptions(task)
+	must.EqError(t, err, fmt.Sprintf("task unveil entry %q is not allowed: path %s is denied by driver config", "rx:"+sh, sh))
+}
+
+func Test_unveilExpansion(t *testing.T) {
+	dir := t.TempDir()
+	for i := range 5 {
+		must.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d", i)), nil, 0o644))
+	}
+
+	must.Eq(t, 0, unveilExpansion(&shim.Options{UnveilPaths: []string{"r:" + dir}}))
+	must.Eq(t, 4, unveilExpansion(&shim.Options{UnveilPaths: []string{"r:" + dir, "!" + dir + "/file0"}}))
+}
@@ -0,0 +1,112 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/hashicorp/nomad-driver-exec2/pkg/shim"
+	"github.com/hashicorp/nomad/helper/users/dynamic"
+	"github.com/hashicorp/nomad/plugins/drivers"
+)
+
+const (
+	// etcPasswd is the file containing the users of the system
+	etcPasswd = "/etc/passwd"
+
+	// etcGroup is the file containing the groups of the system
+	etcGroup = "/etc/group"
+)
+
+// dynamicUser returns the uid (and gid) of the task user, if it is a dynamic
+// workload user synthesized by nomad.
+func dynamicUser(config *drivers.TaskConfig) (int, bool) {
+	ugid, err := dynamic.Parse(config.User)
+	if err != nil {
+		return 0, false
+	}
+	return int(ugid), true
+}
+
+// taskHome returns the home directory of a task running as a dynamic workload
+// user, which is made next to the task directory. Other users keep the home
+// directory of their passwd entry.
+func taskHome(config *drivers.TaskConfig) string {
+	if _, ok := dynamicUser(config); !ok {
+		return ""
+	}
+	parent := filepath.Dir(config.Env["NOMAD_TASK_DIR"])
+	return filepath.Join(parent, "home")
+}
+
+// users writes copies of the passwd and group files of the host with the
+// dynamic workload user of the task appended, and returns the mounts replacing
+// the files of the host with them.
+// The dynamic workload user otherwise has no entry, which breaks tools like
+// whoami and user lookups in the JVM.
+func users(config *drivers.TaskConfig) ([]shim.Mount, error) {
+	id, ok := dynamicUser(config)
+	if !ok {
+		return nil, nil
+	}
+
+	// create the home directory of the user
+	home := taskHome(config)
+	if err := os.MkdirAll(home, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create home directory: %w", err)
+	}
+	if err := os.Chown(home, id, id); err != nil {
+		return nil, fmt.Errorf("failed to set home directory ownership: %w", err)
+	}
+
+	var mounts []shim.Mount
+	files := []struct {
+		name   string
+		target string
+		entry  string
+	}{
+		{name: "passwd", target: etcPasswd, entry: passwdEntry(config.User, id, home)},
+		{name: "group", target: etcGroup, entry: groupEntry(config.User, id)},
+	}
+	for _, file := range files {
+		host, err := os.ReadFile(file.target)
+		if errors.Is(err, os.ErrNotExist) {
+			continue // nothing to mount over
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read %s file: %w", file.name, err)
+		}
+		path := filepath.Join(config.TaskDir().Dir, file.name)
+		if err := os.WriteFile(path, appendEntry(host, file.entry), 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write %s file: %w", file.name, err)
+		}
+		mounts = append(mounts, shim.BindMount(path, file.target, true)...)
+	}
+	return mounts, nil
+}
+
+// appendEntry returns the content of a host passwd or group file with the
+// given entry appended as its last line.
+func appendEntry(host []byte, entry string) []byte {
+	content := slices.Clone(host)
+	if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+		content = append(content, '\n')
+	}
+	return append(content, entry...)
+}
+
+// passwdEntry returns the passwd entry of the given dynamic workload user.
+func passwdEntry(user string, id int, home string) string {
+	return fmt.Sprintf("%s:x:%d:%d:nomad workload user:%s:/bin/sh\n", user, id, id, home)
+}
+
+// groupEntry returns the group entry of the primary group of the given
+// dynamic workload user.
+func groupEntry(user string, id int) string {
+	return fmt.Sprintf("%s:x:%d:\n", user, id)
+}
@@ -0,0 +1,43 @@
+// Copyright IBM Corp. 2024, 2026
+// SPDX-License-Identifier: MPL-2.0
+
+package plugin
+
+import (
+	"testing"
+
+	"github.com/shoenig/test/must"
+)
+
+func Test_appendEntry(t *testing.T) {
+	entry := passwdEntry("nomad-80000", 80000, "/alloc/task/home")
+	must.Eq(t, "nomad-80000:x:80000:80000:nomad workload user:/alloc/task/home:/bin/sh\n", entry)
+
+	cases := []struct {
+		name string
+		host string
+		exp  string
+	}{
+		{
+			name: "host entries",
+			host: "root:x:0:0:root:/root:/bin/bash\nalice:x:1000:1000::/home/alice:/bin/bash\n",
+			exp:  "root:x:0:0:root:/root:/bin/bash\nalice:x:1000:1000::/home/alice:/bin/bash\n" + entry,
+		},
+		{
+			name: "no trailing newline",
+			host: "root:x:0:0:root:/root:/bin/bash",
+			exp:  "root:x:0:0:root:/root:/bin/bash\n" + entry,
+		},
+		{
+			name: "empty",
+			host: "",
+			exp:  entry,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			must.Eq(t, tc.exp, string(appendEntry([]byte(tc.host), entry)))
+		})
+	}
+}